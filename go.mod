@@ -4,11 +4,21 @@ go 1.22
 
 toolchain go1.23.0
 
-require github.com/openai/openai-go/v3 v3.5.0
+require (
+	github.com/bmatcuk/doublestar/v4 v4.10.0
+	github.com/dlclark/regexp2 v1.11.5
+	github.com/openai/openai-go/v3 v3.5.0
+	github.com/santhosh-tekuri/jsonschema/v6 v6.0.2
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
+	github.com/Azure/azure-sdk-for-go/sdk/azcore v1.17.0 // indirect
+	github.com/Azure/azure-sdk-for-go/sdk/internal v1.10.0 // indirect
 	github.com/tidwall/gjson v1.14.4 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.1 // indirect
 	github.com/tidwall/sjson v1.2.5 // indirect
+	golang.org/x/net v0.34.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
 )
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/openai"
+)
+
+// Agent answers a request in a loop, running the tools called by the model
+// in the workspace and sending their results back until the model answers
+type Agent struct {
+	provider      openai.Provider
+	workspace     *Workspace
+	maxIterations int
+	logger        *slog.Logger
+}
+
+// New creates an agent that makes at most maxIterations requests
+func New(provider openai.Provider, workspace *Workspace, maxIterations int, logger *slog.Logger) *Agent {
+	return &Agent{
+		provider:      provider,
+		workspace:     workspace,
+		maxIterations: maxIterations,
+		logger:        logger,
+	}
+}
+
+// Run sends the request with the workspace tools and returns the final
+// answer. Usage is summed over all requests.
+func (a *Agent) Run(ctx context.Context, req openai.ChatCompletionRequest) (*openai.ChatCompletionResponse, error) {
+	req.Tools = a.workspace.Tools()
+	req.Messages = append([]openai.Message{}, req.Messages...)
+
+	var usage openai.Usage
+	for iteration := 1; iteration <= a.maxIterations; iteration++ {
+		resp, err := a.provider.CreateChatCompletion(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		usage.PromptTokens += resp.Usage.PromptTokens
+		usage.CompletionTokens += resp.Usage.CompletionTokens
+		usage.TotalTokens += resp.Usage.TotalTokens
+
+		if len(resp.ToolCalls) == 0 {
+			a.logger.Info("agent finished", "iterations", iteration, "total_tokens", usage.TotalTokens)
+			resp.Usage = usage
+			return resp, nil
+		}
+
+		a.logger.Info("agent calling tools", "iteration", iteration, "tool_calls", len(resp.ToolCalls))
+		req.Messages = append(req.Messages, openai.Message{
+			Role:      "assistant",
+			Content:   resp.Content,
+			ToolCalls: resp.ToolCalls,
+		})
+		for _, call := range resp.ToolCalls {
+			// Tool errors are reported to the model, which can try again
+			output, err := a.workspace.Call(ctx, call.Name, call.Arguments)
+			if err != nil {
+				a.logger.Warn("tool call failed", "tool", call.Name, "error", err)
+				output = "error: " + err.Error()
+			}
+			req.Messages = append(req.Messages, openai.Message{
+				Role:       "tool",
+				Content:    output,
+				ToolCallID: call.ID,
+			})
+		}
+	}
+	return nil, fmt.Errorf("no final answer after %d iterations, increase MAX_ITERATIONS", a.maxIterations)
+}
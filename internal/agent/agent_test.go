@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/openai"
+)
+
+// scriptedProvider returns the scripted responses in order and records the
+// requests
+type scriptedProvider struct {
+	responses []*openai.ChatCompletionResponse
+	requests  []openai.ChatCompletionRequest
+}
+
+func (p *scriptedProvider) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (*openai.ChatCompletionResponse, error) {
+	p.requests = append(p.requests, req)
+	if len(p.responses) == 0 {
+		return nil, errors.New("no more responses")
+	}
+	resp := p.responses[0]
+	p.responses = p.responses[1:]
+	return resp, nil
+}
+
+func toolCall(id, name, arguments string) *openai.ChatCompletionResponse {
+	return &openai.ChatCompletionResponse{
+		ToolCalls: []openai.ToolCall{{ID: id, Name: name, Arguments: arguments}},
+		Usage:     openai.Usage{TotalTokens: 10},
+	}
+}
+
+func TestAgent_Run(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	provider := &scriptedProvider{responses: []*openai.ChatCompletionResponse{
+		toolCall("call_1", "read_file", `{"path": "main.go"}`),
+		toolCall("call_2", "read_file", `{"path": "../outside.txt"}`),
+		{Content: "Looks good", Usage: openai.Usage{TotalTokens: 5}},
+	}}
+	a := New(provider, newTestWorkspace(t), 5, logger)
+
+	req := openai.ChatCompletionRequest{Messages: []openai.Message{{Role: "user", Content: "Review main.go"}}}
+	resp, err := a.Run(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if resp.Content != "Looks good" || resp.Usage.TotalTokens != 25 {
+		t.Errorf("Run() = %q with %d tokens, want the final answer with the summed usage", resp.Content, resp.Usage.TotalTokens)
+	}
+	if len(req.Messages) != 1 {
+		t.Error("Run() should not modify the caller's messages")
+	}
+
+	if len(provider.requests) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(provider.requests))
+	}
+	if len(provider.requests[0].Tools) != 4 {
+		t.Errorf("Expected the 4 workspace tools, got %d", len(provider.requests[0].Tools))
+	}
+	messages := provider.requests[2].Messages
+	if len(messages) != 5 {
+		t.Fatalf("Expected the prompt and two tool exchanges, got %d messages", len(messages))
+	}
+	if messages[1].Role != "assistant" || messages[1].ToolCalls[0].ID != "call_1" {
+		t.Errorf("Message 1 should be the tool call, got %+v", messages[1])
+	}
+	if messages[2].Role != "tool" || messages[2].ToolCallID != "call_1" || !strings.Contains(messages[2].Content.(string), "package main") {
+		t.Errorf("Message 2 should be the file content, got %+v", messages[2])
+	}
+	if got := messages[4].Content.(string); !strings.HasPrefix(got, "error: ") || !strings.Contains(got, "outside the workspace") {
+		t.Errorf("Tool errors should be reported to the model, got %q", got)
+	}
+}
+
+func TestAgent_MaxIterations(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	provider := &scriptedProvider{responses: []*openai.ChatCompletionResponse{
+		toolCall("call_1", "list_dir", `{}`),
+		toolCall("call_2", "list_dir", `{}`),
+		toolCall("call_3", "list_dir", `{}`),
+	}}
+	a := New(provider, newTestWorkspace(t), 2, logger)
+
+	_, err := a.Run(context.Background(), openai.ChatCompletionRequest{})
+	if err == nil || !strings.Contains(err.Error(), "no final answer after 2 iterations") {
+		t.Errorf("Run() error = %v, want the iteration limit", err)
+	}
+	if len(provider.requests) != 2 {
+		t.Errorf("Expected 2 requests, got %d", len(provider.requests))
+	}
+}
@@ -0,0 +1,361 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/openai"
+)
+
+const (
+	// maxOutput caps the size of a tool result sent back to the model
+	maxOutput = 32 * 1024
+	// maxMatches caps the number of lines returned by grep
+	maxMatches = 200
+	// maxGrepFileSize skips large files, which are usually generated
+	maxGrepFileSize = 1 << 20
+	// maxLogCount caps the number of commits returned by git_log
+	maxLogCount = 50
+)
+
+// Workspace runs the built-in tools against a directory. Paths given by the
+// model are resolved relative to the root and may not leave it, including
+// through symlinks.
+type Workspace struct {
+	root   string
+	logger *slog.Logger
+}
+
+// NewWorkspace creates a workspace rooted at dir
+func NewWorkspace(dir string, logger *slog.Logger) (*Workspace, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving workspace: %w", err)
+	}
+	root, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving workspace: %w", err)
+	}
+	return &Workspace{root: root, logger: logger}, nil
+}
+
+// Tools describes the built-in tools to the model
+func (w *Workspace) Tools() []openai.Tool {
+	return []openai.Tool{
+		{
+			Name:        "read_file",
+			Description: "Read a text file from the repository. Lines are prefixed with their line number.",
+			Parameters: object(map[string]any{
+				"path":       stringParam("File path relative to the repository root"),
+				"start_line": intParam("First line to read, starting at 1"),
+				"end_line":   intParam("Last line to read"),
+			}, "path"),
+		},
+		{
+			Name:        "list_dir",
+			Description: "List the entries of a directory in the repository. Directories end with a slash.",
+			Parameters: object(map[string]any{
+				"path": stringParam("Directory path relative to the repository root, defaults to the root"),
+			}),
+		},
+		{
+			Name:        "grep",
+			Description: "Search the repository for lines matching a regular expression (Go RE2 syntax).",
+			Parameters: object(map[string]any{
+				"pattern": stringParam("Regular expression to search for"),
+				"path":    stringParam("Directory or file to search, defaults to the root"),
+				"glob":    stringParam("Only search files matching this glob, e.g. **/*.go"),
+			}, "pattern"),
+		},
+		{
+			Name:        "git_log",
+			Description: "Show recent commits of the repository, optionally limited to a path.",
+			Parameters: object(map[string]any{
+				"path":      stringParam("File or directory to show the history of"),
+				"max_count": intParam("Number of commits to show, at most 50"),
+			}),
+		},
+	}
+}
+
+// Call runs a tool with its JSON encoded arguments and returns its output
+func (w *Workspace) Call(ctx context.Context, name, arguments string) (string, error) {
+	var args struct {
+		Path      string `json:"path"`
+		StartLine int    `json:"start_line"`
+		EndLine   int    `json:"end_line"`
+		Pattern   string `json:"pattern"`
+		Glob      string `json:"glob"`
+		MaxCount  int    `json:"max_count"`
+	}
+	if strings.TrimSpace(arguments) != "" {
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+
+	w.logger.Info("running tool", "tool", name, "arguments", arguments)
+	var output string
+	var err error
+	switch name {
+	case "read_file":
+		output, err = w.readFile(args.Path, args.StartLine, args.EndLine)
+	case "list_dir":
+		output, err = w.listDir(args.Path)
+	case "grep":
+		output, err = w.grep(args.Pattern, args.Path, args.Glob)
+	case "git_log":
+		output, err = w.gitLog(ctx, args.Path, args.MaxCount)
+	default:
+		return "", fmt.Errorf("unknown tool %q", name)
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(output) > maxOutput {
+		output = truncateUTF8(output, maxOutput) + "\n... [output truncated] ..."
+	}
+	return output, nil
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// Resolve maps a path relative to the root to its real path inside the
+// workspace. Paths leaving the workspace, directly or through a symlink, are
+// refused.
+func (w *Workspace) Resolve(path string) (string, error) {
+	if path == "" {
+		path = "."
+	}
+	joined := filepath.Clean(filepath.FromSlash(path))
+	if !filepath.IsAbs(joined) {
+		joined = filepath.Join(w.root, joined)
+	}
+	if !w.contains(joined) {
+		return "", fmt.Errorf("path %q is outside the workspace", path)
+	}
+	real, err := filepath.EvalSymlinks(joined)
+	if err != nil {
+		return "", fmt.Errorf("path %q does not exist", path)
+	}
+	if !w.contains(real) {
+		return "", fmt.Errorf("path %q is outside the workspace", path)
+	}
+	return real, nil
+}
+
+// contains reports whether path is the root or below it
+func (w *Workspace) contains(path string) bool {
+	rel, err := filepath.Rel(w.root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// relative returns the slash separated path of a file below the root
+func (w *Workspace) relative(path string) string {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (w *Workspace) readFile(path string, start, end int) (string, error) {
+	resolved, err := w.Resolve(path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %w", path, err)
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "", fmt.Errorf("%s is a binary file", path)
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if start < 1 {
+		start = 1
+	}
+	if end < 1 || end > len(lines) {
+		end = len(lines)
+	}
+	var b strings.Builder
+	for i := start; i <= end; i++ {
+		fmt.Fprintf(&b, "%d\t%s\n", i, strings.TrimSuffix(lines[i-1], "\n"))
+	}
+	if b.Len() == 0 {
+		return fmt.Sprintf("%s has %d lines", path, len(lines)), nil
+	}
+	return b.String(), nil
+}
+
+func (w *Workspace) listDir(path string) (string, error) {
+	resolved, err := w.Resolve(path)
+	if err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(resolved)
+	if err != nil {
+		return "", fmt.Errorf("error listing %s: %w", path, err)
+	}
+	var b strings.Builder
+	for _, entry := range entries {
+		if entry.Name() == ".git" {
+			continue
+		}
+		b.WriteString(entry.Name())
+		if entry.IsDir() {
+			b.WriteString("/")
+		}
+		b.WriteString("\n")
+	}
+	if b.Len() == 0 {
+		return "(empty directory)", nil
+	}
+	return b.String(), nil
+}
+
+func (w *Workspace) grep(pattern, path, glob string) (string, error) {
+	if pattern == "" {
+		return "", fmt.Errorf("pattern is required")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %w", err)
+	}
+	if glob != "" && !doublestar.ValidatePattern(glob) {
+		return "", fmt.Errorf("invalid glob %q", glob)
+	}
+	resolved, err := w.Resolve(path)
+	if err != nil {
+		return "", err
+	}
+
+	var matches []string
+	err = filepath.WalkDir(resolved, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel := w.relative(file)
+		if glob != "" {
+			if ok, _ := doublestar.Match(glob, rel); !ok {
+				return nil
+			}
+		}
+		if info, err := entry.Info(); err != nil || !info.Mode().IsRegular() || info.Size() > maxGrepFileSize {
+			return nil
+		}
+		data, err := os.ReadFile(file)
+		if err != nil || bytes.IndexByte(data, 0) >= 0 {
+			return nil
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), maxGrepFileSize)
+		for n := 1; scanner.Scan(); n++ {
+			if re.MatchString(scanner.Text()) {
+				matches = append(matches, rel+":"+strconv.Itoa(n)+": "+scanner.Text())
+				if len(matches) >= maxMatches {
+					return fs.SkipAll
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error searching %s: %w", path, err)
+	}
+	if len(matches) == 0 {
+		return "No matches", nil
+	}
+	sort.Strings(matches)
+	output := strings.Join(matches, "\n")
+	if len(matches) >= maxMatches {
+		output += fmt.Sprintf("\n... [stopped after %d matches] ...", maxMatches)
+	}
+	return output, nil
+}
+
+func (w *Workspace) gitLog(ctx context.Context, path string, count int) (string, error) {
+	if count <= 0 {
+		count = 10
+	}
+	if count > maxLogCount {
+		count = maxLogCount
+	}
+	args := []string{"log", "--no-color", "--max-count=" + strconv.Itoa(count), "--date=short", "--format=%h %ad %an%n    %s"}
+	if path != "" {
+		resolved, err := w.Resolve(path)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, "--", resolved)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = w.root
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git log failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if len(out) == 0 {
+		return "No commits", nil
+	}
+	return string(out), nil
+}
+
+// object builds the JSON Schema of a tool's arguments
+func object(properties map[string]any, required ...string) map[string]any {
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func stringParam(description string) map[string]any {
+	return map[string]any{"type": "string", "description": description}
+}
+
+func intParam(description string) map[string]any {
+	return map[string]any{"type": "integer", "description": description}
+}
@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// newTestWorkspace creates a workspace with a few files and a symlink that
+// points outside of it
+func newTestWorkspace(t *testing.T) *Workspace {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":        "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n",
+		"pkg/util.go":    "package pkg\n\n// TODO: remove\nfunc Util() {}\n",
+		"docs/README.md": "# Docs\nTODO: write\n",
+		"bin/tool":       "\x00\x01binary",
+		"../outside.txt": "secret",
+		".git/HEAD":      "ref: refs/heads/main\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, "..", "outside.txt"), filepath.Join(dir, "link.txt")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	w, err := NewWorkspace(dir, logger)
+	if err != nil {
+		t.Fatalf("NewWorkspace() error = %v", err)
+	}
+	return w
+}
+
+func TestWorkspace_Call(t *testing.T) {
+	w := newTestWorkspace(t)
+
+	tests := []struct {
+		name      string
+		tool      string
+		arguments string
+		want      []string
+		wantErr   string
+	}{
+		{"read file", "read_file", `{"path": "main.go"}`, []string{"1\tpackage main\n", "4\t\tprintln(\"hello\")\n"}, ""},
+		{"read lines", "read_file", `{"path": "main.go", "start_line": 3, "end_line": 3}`, []string{"3\tfunc main() {\n"}, ""},
+		{"read binary file", "read_file", `{"path": "bin/tool"}`, nil, "binary file"},
+		{"read missing file", "read_file", `{"path": "missing.go"}`, nil, "does not exist"},
+		{"read outside", "read_file", `{"path": "../outside.txt"}`, nil, "outside the workspace"},
+		{"read absolute outside", "read_file", `{"path": "/etc/passwd"}`, nil, "outside the workspace"},
+		{"read symlink outside", "read_file", `{"path": "link.txt"}`, nil, "outside the workspace"},
+		{"list root", "list_dir", `{}`, []string{"main.go\n", "pkg/\n", "docs/\n"}, ""},
+		{"list subdirectory", "list_dir", `{"path": "pkg"}`, []string{"util.go\n"}, ""},
+		{"grep", "grep", `{"pattern": "TODO"}`, []string{"docs/README.md:2: TODO: write", "pkg/util.go:3: // TODO: remove"}, ""},
+		{"grep with glob", "grep", `{"pattern": "TODO", "glob": "**/*.go"}`, []string{"pkg/util.go:3"}, ""},
+		{"grep without matches", "grep", `{"pattern": "FIXME"}`, []string{"No matches"}, ""},
+		{"grep invalid pattern", "grep", `{"pattern": "("}`, nil, "invalid pattern"},
+		{"unknown tool", "rm", `{}`, nil, "unknown tool"},
+		{"invalid arguments", "read_file", `{"path": 1}`, nil, "invalid arguments"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := w.Call(context.Background(), tt.tool, tt.arguments)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Call() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Call() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("Call() = %q, want it to contain %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkspace_TruncatesOutput(t *testing.T) {
+	w := newTestWorkspace(t)
+	// The leading "a" shifts the multi-byte characters off the limit
+	if err := os.WriteFile(filepath.Join(w.root, "big.txt"), []byte("a"+strings.Repeat("€", maxOutput)), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	got, err := w.Call(context.Background(), "read_file", `{"path": "big.txt"}`)
+	if err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if !strings.HasSuffix(got, "[output truncated] ...") || len(got) > maxOutput+100 {
+		t.Errorf("Expected truncated output, got %d bytes", len(got))
+	}
+	if !utf8.ValidString(got) {
+		t.Error("Truncated output is not valid UTF-8")
+	}
+}
+
+func TestWorkspace_ListDirSkipsGit(t *testing.T) {
+	w := newTestWorkspace(t)
+
+	got, err := w.Call(context.Background(), "list_dir", `{"path": "."}`)
+	if err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if strings.Contains(got, ".git") {
+		t.Errorf("list_dir should skip .git, got %q", got)
+	}
+}
+
+func TestWorkspace_GitLog(t *testing.T) {
+	w := newTestWorkspace(t)
+	os.RemoveAll(filepath.Join(w.root, ".git"))
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "main.go"},
+		{"-c", "user.name=Octocat", "-c", "user.email=octocat@example.com", "commit", "-q", "-m", "Add main"},
+		{"add", "."},
+		{"-c", "user.name=Octocat", "-c", "user.email=octocat@example.com", "commit", "-q", "-m", "Add the rest"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = w.root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	got, err := w.Call(context.Background(), "git_log", `{}`)
+	if err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if !strings.Contains(got, "Add the rest") || !strings.Contains(got, "Add main") || !strings.Contains(got, "Octocat") {
+		t.Errorf("git_log = %q, want both commits", got)
+	}
+
+	got, err = w.Call(context.Background(), "git_log", `{"path": "pkg", "max_count": 5}`)
+	if err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if strings.Contains(got, "Add main") {
+		t.Errorf("git_log for pkg should only show the second commit, got %q", got)
+	}
+
+	if _, err := w.Call(context.Background(), "git_log", `{"path": "../outside.txt"}`); err == nil {
+		t.Error("Expected error for a path outside the workspace, got nil")
+	}
+}
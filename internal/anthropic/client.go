@@ -0,0 +1,256 @@
+package anthropic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/openai"
+)
+
+const (
+	defaultBaseURL   = "https://api.anthropic.com"
+	apiVersion       = "2023-06-01"
+	defaultMaxTokens = 1024
+)
+
+// Options holds the connection settings for the Anthropic API
+type Options struct {
+	APIKey  string
+	BaseURL string
+	Headers map[string]string
+}
+
+// Client calls the Anthropic Messages API
+type Client struct {
+	opts       Options
+	httpClient *http.Client
+	logger     *slog.Logger
+}
+
+// NewClient creates a new Anthropic client
+func NewClient(opts Options, logger *slog.Logger) *Client {
+	if opts.BaseURL == "" {
+		opts.BaseURL = defaultBaseURL
+	}
+	opts.BaseURL = strings.TrimSuffix(opts.BaseURL, "/")
+	return &Client{
+		opts:       opts,
+		httpClient: http.DefaultClient,
+		logger:     logger,
+	}
+}
+
+// messagesRequest is the body of a Messages API request
+type messagesRequest struct {
+	Model       string    `json:"model"`
+	System      string    `json:"system,omitempty"`
+	Messages    []message `json:"messages"`
+	MaxTokens   int64     `json:"max_tokens"`
+	Temperature *float64  `json:"temperature,omitempty"`
+}
+
+// message is a single conversation turn
+type message struct {
+	Role    string         `json:"role"`
+	Content []contentBlock `json:"content"`
+}
+
+// contentBlock is a text or image block within a message
+type contentBlock struct {
+	Type   string       `json:"type"`
+	Text   string       `json:"text,omitempty"`
+	Source *imageSource `json:"source,omitempty"`
+}
+
+// imageSource describes where the image data comes from
+type imageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// messagesResponse is the body of a successful Messages API response
+type messagesResponse struct {
+	Model      string         `json:"model"`
+	Content    []contentBlock `json:"content"`
+	StopReason string         `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int64 `json:"input_tokens"`
+		OutputTokens int64 `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// errorResponse is the body returned when a request fails
+type errorResponse struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// CreateChatCompletion sends a request to the Messages API and returns the response
+func (c *Client) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (*openai.ChatCompletionResponse, error) {
+	c.logger.Info("creating anthropic message",
+		"model", req.Model,
+		"temperature", req.Temperature,
+		"max_tokens", req.MaxTokens,
+		"num_messages", len(req.Messages),
+	)
+
+	body := buildRequest(req)
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.opts.BaseURL+"/v1/messages", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", c.opts.APIKey)
+	httpReq.Header.Set("anthropic-version", apiVersion)
+	for key, value := range c.opts.Headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		c.logger.Error("Anthropic API call failed", "error", err)
+		return nil, fmt.Errorf("Anthropic API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr errorResponse
+		message := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			message = apiErr.Error.Type + ": " + apiErr.Error.Message
+		}
+		c.logger.Error("Anthropic API call failed", "status", resp.StatusCode, "error", message)
+		return nil, &openai.APIError{
+			Provider:   "Anthropic",
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+			Message:    message,
+		}
+	}
+
+	var result messagesResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	var content strings.Builder
+	for _, block := range result.Content {
+		if block.Type == "text" {
+			content.WriteString(block.Text)
+		}
+	}
+	if content.Len() == 0 {
+		c.logger.Warn("empty content in response")
+		return nil, fmt.Errorf("empty response from Anthropic")
+	}
+
+	usage := openai.Usage{
+		PromptTokens:     result.Usage.InputTokens,
+		CompletionTokens: result.Usage.OutputTokens,
+		TotalTokens:      result.Usage.InputTokens + result.Usage.OutputTokens,
+	}
+	c.logger.Info("anthropic message successful",
+		"prompt_tokens", usage.PromptTokens,
+		"completion_tokens", usage.CompletionTokens,
+		"total_tokens", usage.TotalTokens,
+		"stop_reason", result.StopReason,
+	)
+
+	return &openai.ChatCompletionResponse{
+		Content:      content.String(),
+		Model:        result.Model,
+		FinishReason: result.StopReason,
+		Usage:        usage,
+	}, nil
+}
+
+// buildRequest converts a chat completion request into a Messages API request.
+// System messages move to the top-level system field.
+func buildRequest(req openai.ChatCompletionRequest) messagesRequest {
+	body := messagesRequest{
+		Model:     req.Model,
+		MaxTokens: req.MaxTokens,
+	}
+	if body.MaxTokens <= 0 {
+		body.MaxTokens = defaultMaxTokens
+	}
+	if req.Temperature > 0 {
+		temperature := req.Temperature
+		body.Temperature = &temperature
+	}
+
+	var system []string
+	for _, msg := range req.Messages {
+		if msg.Role == "system" {
+			if text, ok := msg.Content.(string); ok && text != "" {
+				system = append(system, text)
+			}
+			continue
+		}
+		body.Messages = append(body.Messages, convertMessage(msg))
+	}
+	if req.ResponseSchema != nil {
+		// The Messages API has no JSON schema response format, so the schema is
+		// given as an instruction and the response is validated by the caller
+		schema, _ := json.MarshalIndent(req.ResponseSchema.Schema, "", "  ")
+		system = append(system, "Respond only with a JSON document that matches this JSON Schema, without any other text:\n"+string(schema))
+	}
+	body.System = strings.Join(system, "\n\n")
+	return body
+}
+
+// convertMessage converts our internal Message type to Anthropic content blocks
+func convertMessage(msg openai.Message) message {
+	role := msg.Role
+	if role != "assistant" {
+		role = "user"
+	}
+
+	parts, ok := msg.Content.([]openai.MessagePart)
+	if !ok {
+		text, _ := msg.Content.(string)
+		return message{Role: role, Content: []contentBlock{{Type: "text", Text: text}}}
+	}
+
+	blocks := make([]contentBlock, 0, len(parts))
+	for _, part := range parts {
+		if part.Type == "text" {
+			blocks = append(blocks, contentBlock{Type: "text", Text: part.Text})
+		} else if part.Type == "image_url" && part.ImageURL != nil {
+			blocks = append(blocks, contentBlock{Type: "image", Source: imageSourceFromURL(part.ImageURL.URL)})
+		}
+	}
+	return message{Role: role, Content: blocks}
+}
+
+// imageSourceFromURL converts a data URL produced by the file processor into
+// a base64 image source. Other URLs are passed by reference.
+func imageSourceFromURL(url string) *imageSource {
+	if rest, ok := strings.CutPrefix(url, "data:"); ok {
+		mediaType, data, found := strings.Cut(rest, ";base64,")
+		if found {
+			return &imageSource{Type: "base64", MediaType: mediaType, Data: data}
+		}
+	}
+	return &imageSource{Type: "url", URL: url}
+}
@@ -0,0 +1,138 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/openai"
+)
+
+func TestCreateChatCompletion(t *testing.T) {
+	var received messagesRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("Path = %q, want /v1/messages", r.URL.Path)
+		}
+		if got := r.Header.Get("x-api-key"); got != "test-key" {
+			t.Errorf("x-api-key = %q, want test-key", got)
+		}
+		if got := r.Header.Get("anthropic-version"); got != apiVersion {
+			t.Errorf("anthropic-version = %q, want %q", got, apiVersion)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"model": "claude-sonnet-4-5",
+			"content": [{"type": "text", "text": "Hello from Claude"}],
+			"stop_reason": "end_turn",
+			"usage": {"input_tokens": 12, "output_tokens": 4}
+		}`))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	client := NewClient(Options{APIKey: "test-key", BaseURL: server.URL}, logger)
+
+	resp, err := client.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model: "claude-sonnet-4-5",
+		Messages: []openai.Message{
+			{Role: "system", Content: "You are a reviewer."},
+			{Role: "user", Content: []openai.MessagePart{
+				{Type: "text", Text: "Describe this image"},
+				{Type: "image_url", ImageURL: &openai.ImageURL{URL: "data:image/png;base64,aGVsbG8="}},
+			}},
+		},
+		Temperature: 0.2,
+		MaxTokens:   500,
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion() error = %v", err)
+	}
+
+	if received.System != "You are a reviewer." {
+		t.Errorf("System = %q, want system prompt", received.System)
+	}
+	if received.MaxTokens != 500 {
+		t.Errorf("MaxTokens = %d, want 500", received.MaxTokens)
+	}
+	if len(received.Messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(received.Messages))
+	}
+	blocks := received.Messages[0].Content
+	if len(blocks) != 2 {
+		t.Fatalf("Expected 2 content blocks, got %d", len(blocks))
+	}
+	if blocks[1].Type != "image" || blocks[1].Source == nil {
+		t.Fatalf("Second block should be an image, got %+v", blocks[1])
+	}
+	if blocks[1].Source.Type != "base64" || blocks[1].Source.MediaType != "image/png" || blocks[1].Source.Data != "aGVsbG8=" {
+		t.Errorf("Image source = %+v, want base64 image/png", blocks[1].Source)
+	}
+
+	if resp.Content != "Hello from Claude" {
+		t.Errorf("Content = %q, want %q", resp.Content, "Hello from Claude")
+	}
+	if resp.Usage.PromptTokens != 12 || resp.Usage.CompletionTokens != 4 || resp.Usage.TotalTokens != 16 {
+		t.Errorf("Usage = %+v, want 12/4/16", resp.Usage)
+	}
+	if resp.FinishReason != "end_turn" {
+		t.Errorf("FinishReason = %q, want end_turn", resp.FinishReason)
+	}
+}
+
+func TestCreateChatCompletion_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"type": "error", "error": {"type": "invalid_request_error", "message": "bad model"}}`))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	client := NewClient(Options{APIKey: "test-key", BaseURL: server.URL}, logger)
+
+	_, err := client.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model:    "missing",
+		Messages: []openai.Message{{Role: "user", Content: "Hello"}},
+	})
+	if err == nil {
+		t.Fatal("Expected error for failed request, got nil")
+	}
+}
+
+func TestBuildRequest_ResponseSchema(t *testing.T) {
+	body := buildRequest(openai.ChatCompletionRequest{
+		Model: "claude-sonnet-4-5",
+		Messages: []openai.Message{
+			{Role: "system", Content: "You are a reviewer."},
+			{Role: "user", Content: "Review"},
+		},
+		ResponseSchema: &openai.JSONSchema{Name: "verdict", Schema: map[string]any{"type": "object"}},
+	})
+	if !strings.HasPrefix(body.System, "You are a reviewer.") {
+		t.Errorf("System should keep the system prompt, got %q", body.System)
+	}
+	if !strings.Contains(body.System, `"type": "object"`) {
+		t.Errorf("System should contain the schema, got %q", body.System)
+	}
+}
+
+func TestBuildRequest_DefaultMaxTokens(t *testing.T) {
+	body := buildRequest(openai.ChatCompletionRequest{
+		Model:    "claude-sonnet-4-5",
+		Messages: []openai.Message{{Role: "user", Content: "Hello"}},
+	})
+	if body.MaxTokens != defaultMaxTokens {
+		t.Errorf("MaxTokens = %d, want %d", body.MaxTokens, defaultMaxTokens)
+	}
+	if body.Temperature != nil {
+		t.Errorf("Temperature should be omitted when zero, got %v", *body.Temperature)
+	}
+}
@@ -0,0 +1,212 @@
+package budget
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/file"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/tokenizer"
+)
+
+// Policy decides what happens when the input exceeds the token budget
+type Policy string
+
+// Supported budget policies
+const (
+	PolicyError        Policy = "error"         // fail before the request is sent
+	PolicyTruncateHead Policy = "truncate_head" // remove the start of the least relevant files
+	PolicyTruncateTail Policy = "truncate_tail" // remove the end of the least relevant files
+	PolicyDropFiles    Policy = "drop_files"    // leave out the least relevant files
+	PolicyMapReduce    Policy = "map_reduce"    // split the input and combine the partial answers
+)
+
+// ErrOverBudget is returned by Fit when the input does not fit and the
+// policy leaves handling it to the caller
+var ErrOverBudget = errors.New("input is over the token budget")
+
+// ParsePolicy converts a policy name into a Policy
+func ParsePolicy(name string) (Policy, error) {
+	switch p := Policy(strings.ToLower(name)); p {
+	case PolicyError, PolicyTruncateHead, PolicyTruncateTail, PolicyDropFiles, PolicyMapReduce:
+		return p, nil
+	case "":
+		return PolicyError, nil
+	default:
+		return "", fmt.Errorf("unsupported budget policy %q", name)
+	}
+}
+
+// Token costs that are not covered by counting the text itself
+const (
+	messageOverhead    = 4   // role and separators of a chat message
+	attachmentOverhead = 8   // delimiter around each file
+	imageTokens        = 765 // a high detail 512x512 tile image, used for every image
+)
+
+// truncationMarker replaces the removed part of a truncated file
+const truncationMarker = "\n... [truncated to fit the token budget] ...\n"
+
+// Budget limits the number of input tokens sent to the model
+type Budget struct {
+	limit   int
+	policy  Policy
+	counter tokenizer.Counter
+	logger  *slog.Logger
+}
+
+// New creates a budget of limit input tokens
+func New(limit int, policy Policy, counter tokenizer.Counter, logger *slog.Logger) *Budget {
+	return &Budget{
+		limit:   limit,
+		policy:  policy,
+		counter: counter,
+		logger:  logger,
+	}
+}
+
+// Fit returns the attachments to send so that they fit within the budget
+// together with the fixed texts (system prompt and prompt), applying the
+// policy when they do not. The fixed texts are never shortened.
+func (b *Budget) Fit(fixed []string, attachments []file.Attachment) ([]file.Attachment, error) {
+	base := b.base(fixed)
+	costs := make([]int, len(attachments))
+	total := base
+	for i, a := range attachments {
+		costs[i] = b.cost(a)
+		total += costs[i]
+	}
+
+	b.logger.Info("input token count", "tokens", total, "budget", b.limit, "files", len(attachments))
+	if total <= b.limit {
+		return attachments, nil
+	}
+	if base > b.limit {
+		return nil, fmt.Errorf("prompt uses %d tokens, over the budget of %d", base, b.limit)
+	}
+	if b.policy == PolicyError || b.policy == PolicyMapReduce || len(attachments) == 0 {
+		return nil, fmt.Errorf("%w: input uses %d tokens of %d", ErrOverBudget, total, b.limit)
+	}
+
+	overflow := total - b.limit
+	result := make([]file.Attachment, len(attachments))
+	copy(result, attachments)
+	dropped := make([]bool, len(attachments))
+	for _, i := range leastRelevant(strings.Join(fixed, "\n"), attachments) {
+		if overflow <= 0 {
+			break
+		}
+		a := result[i]
+		keep := costs[i] - attachmentOverhead - overflow - b.counter.Count(truncationMarker)
+		if b.policy == PolicyDropFiles || a.IsImage() || keep <= 0 {
+			dropped[i] = true
+			overflow -= costs[i]
+			b.logger.Warn("file dropped to fit the token budget", "path", a.Path, "tokens", costs[i])
+			continue
+		}
+
+		a.Data = []byte(b.truncate(string(a.Data), keep))
+		result[i] = a
+		overflow -= costs[i] - b.cost(a)
+		b.logger.Warn("file truncated to fit the token budget", "path", a.Path, "tokens", costs[i], "kept_tokens", keep)
+	}
+
+	fitted := make([]file.Attachment, 0, len(result))
+	for i, a := range result {
+		if !dropped[i] {
+			fitted = append(fitted, a)
+		}
+	}
+	if len(fitted) == 0 {
+		return nil, fmt.Errorf("no files fit within the budget of %d tokens", b.limit)
+	}
+	return fitted, nil
+}
+
+// Available returns the number of tokens left for attachments next to the
+// fixed texts
+func (b *Budget) Available(fixed []string) int {
+	return b.limit - b.base(fixed)
+}
+
+// base returns the number of tokens used by the fixed texts
+func (b *Budget) base(fixed []string) int {
+	tokens := 0
+	for _, text := range fixed {
+		tokens += b.counter.Count(text) + messageOverhead
+	}
+	return tokens
+}
+
+// cost returns the number of tokens an attachment adds to the request
+func (b *Budget) cost(a file.Attachment) int {
+	if a.IsImage() {
+		return imageTokens
+	}
+	return b.counter.Count(string(a.Data)) + attachmentOverhead
+}
+
+// truncate shortens text to at most keep tokens plus the truncation marker,
+// keeping the start for truncate_tail and the end for truncate_head. The cut
+// is moved to a line boundary when one is available.
+func (b *Budget) truncate(text string, keep int) string {
+	fromEnd := b.policy == PolicyTruncateHead
+	part := func(n int) string {
+		if fromEnd {
+			return text[len(text)-n:]
+		}
+		return text[:n]
+	}
+
+	// Binary search for the longest part that fits
+	lo, hi := 0, len(text)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if b.counter.Count(part(mid)) <= keep {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	kept := part(lo)
+	if fromEnd {
+		for len(kept) > 0 && !utf8.RuneStart(kept[0]) {
+			kept = kept[1:]
+		}
+		if i := strings.IndexByte(kept, '\n'); i >= 0 && i < len(kept)-1 {
+			kept = kept[i+1:]
+		}
+		return strings.TrimPrefix(truncationMarker, "\n") + kept
+	}
+	for len(kept) > 0 && !utf8.ValidString(kept) {
+		kept = kept[:len(kept)-1]
+	}
+	if i := strings.LastIndexByte(kept, '\n'); i > 0 {
+		kept = kept[:i+1]
+	}
+	return strings.TrimSuffix(kept, "\n") + truncationMarker
+}
+
+// leastRelevant orders attachment indexes from least to most relevant. Files
+// named in the prompt are the most relevant, then files matched earlier.
+func leastRelevant(prompt string, attachments []file.Attachment) []int {
+	order := make([]int, len(attachments))
+	mentioned := make([]bool, len(attachments))
+	for i, a := range attachments {
+		order[i] = i
+		mentioned[i] = strings.Contains(prompt, a.Path) || strings.Contains(prompt, filepath.Base(a.Path))
+	}
+	sort.SliceStable(order, func(x, y int) bool {
+		i, j := order[x], order[y]
+		if mentioned[i] != mentioned[j] {
+			return !mentioned[i]
+		}
+		return i > j
+	})
+	return order
+}
@@ -0,0 +1,188 @@
+package budget
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/file"
+)
+
+// wordCounter counts one token per whitespace separated word
+type wordCounter struct{}
+
+func (wordCounter) Count(text string) int {
+	return len(strings.Fields(text))
+}
+
+// lines returns n numbered lines of two words each
+func lines(prefix string, n int) []byte {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteString(prefix + " line\n")
+	}
+	return []byte(b.String())
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Policy
+		wantErr bool
+	}{
+		{"", PolicyError, false},
+		{"error", PolicyError, false},
+		{"TRUNCATE_HEAD", PolicyTruncateHead, false},
+		{"truncate_tail", PolicyTruncateTail, false},
+		{"drop_files", PolicyDropFiles, false},
+		{"map_reduce", PolicyMapReduce, false},
+		{"summarize", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePolicy(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePolicy(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParsePolicy(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	// The fixed prompt costs 2 words plus the message overhead of 4
+	fixed := []string{"review main.go"}
+	attachments := []file.Attachment{
+		{Path: "main.go", Data: lines("main", 20)},   // 40 + 8 tokens
+		{Path: "util.go", Data: lines("util", 20)},   // 40 + 8 tokens
+		{Path: "extra.go", Data: lines("extra", 20)}, // 40 + 8 tokens
+	}
+
+	tests := []struct {
+		name    string
+		limit   int
+		policy  Policy
+		check   func(t *testing.T, fitted []file.Attachment)
+		wantErr bool
+	}{
+		{
+			name:   "fits",
+			limit:  150,
+			policy: PolicyError,
+			check: func(t *testing.T, fitted []file.Attachment) {
+				if len(fitted) != 3 {
+					t.Errorf("Expected all files, got %d", len(fitted))
+				}
+			},
+		},
+		{
+			name:    "error policy",
+			limit:   100,
+			policy:  PolicyError,
+			wantErr: true,
+		},
+		{
+			name:   "drop files keeps the file named in the prompt",
+			limit:  60,
+			policy: PolicyDropFiles,
+			check: func(t *testing.T, fitted []file.Attachment) {
+				if len(fitted) != 1 || fitted[0].Path != "main.go" {
+					t.Errorf("Expected only main.go, got %v", paths(fitted))
+				}
+			},
+		},
+		{
+			name:   "drop files removes later files first",
+			limit:  110,
+			policy: PolicyDropFiles,
+			check: func(t *testing.T, fitted []file.Attachment) {
+				if got := strings.Join(paths(fitted), ","); got != "main.go,util.go" {
+					t.Errorf("Expected main.go and util.go, got %s", got)
+				}
+			},
+		},
+		{
+			name:   "truncate tail keeps the start",
+			limit:  130,
+			policy: PolicyTruncateTail,
+			check: func(t *testing.T, fitted []file.Attachment) {
+				if len(fitted) != 3 {
+					t.Fatalf("Expected all files, got %d", len(fitted))
+				}
+				extra := string(fitted[2].Data)
+				if !strings.HasPrefix(extra, "extra line\n") || !strings.HasSuffix(extra, truncationMarker) {
+					t.Errorf("Expected the start of extra.go and a marker, got %q", extra)
+				}
+				if string(fitted[0].Data) != string(attachments[0].Data) {
+					t.Error("main.go should not be truncated")
+				}
+			},
+		},
+		{
+			name:   "truncate head keeps the end",
+			limit:  130,
+			policy: PolicyTruncateHead,
+			check: func(t *testing.T, fitted []file.Attachment) {
+				extra := string(fitted[2].Data)
+				if !strings.HasPrefix(extra, "... [truncated") || !strings.HasSuffix(extra, "extra line\n") {
+					t.Errorf("Expected a marker and the end of extra.go, got %q", extra)
+				}
+			},
+		},
+		{
+			name:    "prompt over budget",
+			limit:   5,
+			policy:  PolicyDropFiles,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(tt.limit, tt.policy, wordCounter{}, logger)
+			fitted, err := b.Fit(fixed, attachments)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			tt.check(t, fitted)
+
+			total := 6
+			for _, a := range fitted {
+				total += wordCounter{}.Count(string(a.Data)) + attachmentOverhead
+			}
+			if total > tt.limit {
+				t.Errorf("Fitted input uses %d tokens, over the limit of %d", total, tt.limit)
+			}
+		})
+	}
+}
+
+func TestFit_Images(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	attachments := []file.Attachment{
+		{Path: "notes.txt", Data: []byte("short notes")},
+		{Path: "diagram.png", Data: []byte("png"), MimeType: "image/png"},
+	}
+
+	b := New(100, PolicyTruncateTail, wordCounter{}, logger)
+	fitted, err := b.Fit([]string{"describe"}, attachments)
+	if err != nil {
+		t.Fatalf("Fit() error = %v", err)
+	}
+	if len(fitted) != 1 || fitted[0].Path != "notes.txt" {
+		t.Errorf("Expected the image to be dropped, got %v", paths(fitted))
+	}
+}
+
+func paths(attachments []file.Attachment) []string {
+	result := make([]string, len(attachments))
+	for i, a := range attachments {
+		result[i] = a.Path
+	}
+	return result
+}
@@ -1,50 +1,272 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds all configuration for the plugin
 type Config struct {
-	APIKey       string
-	Model        string
-	Prompt       string
-	FilePath     string
-	Temperature  float64
-	MaxTokens    int
-	SystemPrompt string
-	OutputFile   string
-	Timeout      int
+	Provider       string
+	APIKey         string
+	BaseURL        string
+	Organization   string
+	Project        string
+	ExtraHeaders   map[string]string
+	Model          string
+	FallbackModels []string
+	Prompt         string
+	PromptFile     string
+	PromptName     string
+	PromptDir      string
+	FilePath       string
+	Files          []string
+	Source         string
+	DiffContext    int
+	MaxInputTokens int
+	BudgetPolicy   string
+	TokenizerDir   string
+	MaxConcurrency int
+	Agent          bool
+	MaxIterations  int
+	Temperature    float64
+	MaxTokens      int
+	SystemPrompt   string
+	SystemFile     string
+	Messages       string
+	Template       bool
+	TemplateEnv    []string
+	ResponseSchema string
+	Gate           bool
+	FailOn         string
+	OutputFile     string
+	OutputFormat   string
+	Timeout        int
+	Stream         bool
+	Retry          Retry
+	Azure          Azure
+	Publish        Publish
+	Build          Build
+}
+
+// Retry holds the retry policy for failed provider calls
+type Retry struct {
+	MaxRetries int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Azure holds the settings used by the azure provider
+type Azure struct {
+	Endpoint   string
+	Deployment string
+	APIVersion string
+	ADToken    string
+}
+
+// Publish holds the settings for posting the response to the pull request
+type Publish struct {
+	Target string // code host to publish to, empty to disable
+	Token  string
+	APIURL string // API base URL, defaults to the public API of the target
+	Key    string // distinguishes the comments of several steps
+	Review bool   // post gate findings as a review with inline comments
+}
+
+// Build holds metadata about the CI build the plugin runs in
+type Build struct {
+	Repo             string
+	Branch           string
+	CommitSHA        string
+	Number           string
+	CommitBefore     string
+	CommitAfter      string
+	CommitMessage    string
+	Author           string
+	Event            string
+	TargetBranch     string
+	PullRequest      string
+	PullRequestTitle string
+	Workspace        string
+	RepoLink         string
+	SystemHost       string
 }
 
 // Load creates a new Config from environment variables
 func Load() *Config {
 	return &Config{
-		APIKey:       getEnv("PLUGIN_API_KEY", ""),
-		Model:        getEnv("PLUGIN_MODEL", "gpt-4o-mini"),
-		Prompt:       getEnv("PLUGIN_PROMPT", ""),
-		FilePath:     getEnv("PLUGIN_FILE", ""),
-		Temperature:  getEnvFloat("PLUGIN_TEMPERATURE", 0.7),
-		MaxTokens:    getEnvInt("PLUGIN_MAX_TOKENS", 1000),
-		SystemPrompt: getEnv("PLUGIN_SYSTEM_PROMPT", "You are a helpful assistant."),
-		OutputFile:   getEnv("PLUGIN_OUTPUT_FILE", ""),
-		Timeout:      getEnvInt("PLUGIN_TIMEOUT", 60),
+		Provider:       getEnv("PLUGIN_PROVIDER", "openai"),
+		APIKey:         getEnv("PLUGIN_API_KEY", ""),
+		BaseURL:        getEnv("PLUGIN_BASE_URL", ""),
+		Organization:   getEnv("PLUGIN_ORGANIZATION", ""),
+		Project:        getEnv("PLUGIN_PROJECT", ""),
+		ExtraHeaders:   getEnvMap("PLUGIN_EXTRA_HEADERS"),
+		Model:          getEnv("PLUGIN_MODEL", "gpt-4o-mini"),
+		FallbackModels: getEnvSlice("PLUGIN_FALLBACK_MODELS"),
+		Prompt:         getEnv("PLUGIN_PROMPT", ""),
+		PromptFile:     getEnv("PLUGIN_PROMPT_FILE", ""),
+		PromptName:     getEnv("PLUGIN_PROMPT_NAME", ""),
+		PromptDir:      getEnv("PLUGIN_PROMPT_DIR", ".drone/prompts"),
+		FilePath:       getEnv("PLUGIN_FILE", ""),
+		Files:          getEnvSlice("PLUGIN_FILES"),
+		Source:         getEnv("PLUGIN_SOURCE", "files"),
+		DiffContext:    getEnvInt("PLUGIN_DIFF_CONTEXT", 3),
+		MaxInputTokens: getEnvInt("PLUGIN_MAX_INPUT_TOKENS", 0),
+		BudgetPolicy:   getEnv("PLUGIN_BUDGET_POLICY", "error"),
+		TokenizerDir:   getEnv("PLUGIN_TOKENIZER_DIR", ""),
+		MaxConcurrency: getEnvInt("PLUGIN_MAX_CONCURRENCY", 4),
+		Agent:          getEnvBool("PLUGIN_AGENT", false),
+		MaxIterations:  getEnvInt("PLUGIN_MAX_ITERATIONS", 10),
+		Temperature:    getEnvFloat("PLUGIN_TEMPERATURE", 0.7),
+		MaxTokens:      getEnvInt("PLUGIN_MAX_TOKENS", 1000),
+		SystemPrompt:   getEnv("PLUGIN_SYSTEM_PROMPT", "You are a helpful assistant."),
+		SystemFile:     getEnv("PLUGIN_SYSTEM_PROMPT_FILE", ""),
+		Messages:       getEnv("PLUGIN_MESSAGES", ""),
+		Template:       getEnvBool("PLUGIN_TEMPLATE", true),
+		TemplateEnv:    getEnvSlice("PLUGIN_TEMPLATE_ENV"),
+		ResponseSchema: getEnv("PLUGIN_RESPONSE_SCHEMA", ""),
+		Gate:           getEnvBool("PLUGIN_GATE", false),
+		FailOn:         getEnv("PLUGIN_FAIL_ON", "high"),
+		OutputFile:     getEnv("PLUGIN_OUTPUT_FILE", ""),
+		OutputFormat:   getEnv("PLUGIN_OUTPUT_FORMAT", "text"),
+		Timeout:        getEnvInt("PLUGIN_TIMEOUT", 60),
+		Stream:         getEnvBool("PLUGIN_STREAM", false),
+		Retry: Retry{
+			MaxRetries: getEnvInt("PLUGIN_MAX_RETRIES", 2),
+			Backoff:    getEnvDuration("PLUGIN_RETRY_BACKOFF", time.Second),
+			MaxBackoff: getEnvDuration("PLUGIN_RETRY_MAX_BACKOFF", 30*time.Second),
+		},
+		Azure: Azure{
+			Endpoint:   getEnv("PLUGIN_AZURE_ENDPOINT", ""),
+			Deployment: getEnv("PLUGIN_AZURE_DEPLOYMENT", ""),
+			APIVersion: getEnv("PLUGIN_AZURE_API_VERSION", "2024-10-21"),
+			ADToken:    getEnv("PLUGIN_AZURE_AD_TOKEN", ""),
+		},
+		Publish: Publish{
+			Target: getEnv("PLUGIN_PUBLISH", ""),
+			Token:  getEnv("PLUGIN_PUBLISH_TOKEN", ""),
+			APIURL: getEnv("PLUGIN_PUBLISH_API_URL", ""),
+			Key:    getEnv("PLUGIN_PUBLISH_KEY", getEnv("DRONE_STEP_NAME", "review")),
+			Review: getEnvBool("PLUGIN_PUBLISH_REVIEW", false),
+		},
+		Build: loadBuild(),
+	}
+}
+
+// loadBuild reads the build metadata exported by the Drone runner
+func loadBuild() Build {
+	return Build{
+		Repo:             getEnv("DRONE_REPO", ""),
+		Branch:           getEnv("DRONE_BRANCH", ""),
+		CommitSHA:        getEnv("DRONE_COMMIT_SHA", ""),
+		Number:           getEnv("DRONE_BUILD_NUMBER", ""),
+		CommitBefore:     getEnv("DRONE_COMMIT_BEFORE", ""),
+		CommitAfter:      getEnv("DRONE_COMMIT_AFTER", ""),
+		CommitMessage:    getEnv("DRONE_COMMIT_MESSAGE", ""),
+		Author:           getEnv("DRONE_COMMIT_AUTHOR", ""),
+		Event:            getEnv("DRONE_BUILD_EVENT", ""),
+		TargetBranch:     getEnv("DRONE_TARGET_BRANCH", ""),
+		PullRequest:      getEnv("DRONE_PULL_REQUEST", ""),
+		PullRequestTitle: getEnv("DRONE_PULL_REQUEST_TITLE", ""),
+		Workspace:        getEnv("DRONE_WORKSPACE", ""),
+		RepoLink:         getEnv("DRONE_REPO_LINK", ""),
+		SystemHost:       getEnv("DRONE_SYSTEM_HOST", ""),
 	}
 }
 
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
-	if c.APIKey == "" {
-		return fmt.Errorf("API_KEY is required")
+	switch c.Provider {
+	case "", "openai", "anthropic", "gemini":
+		if c.APIKey == "" {
+			return fmt.Errorf("API_KEY is required")
+		}
+	case "ollama":
+		// local servers do not require an API key
+	case "azure":
+		if c.Azure.Endpoint == "" {
+			return fmt.Errorf("AZURE_ENDPOINT is required for the azure provider")
+		}
+		if c.APIKey == "" && c.Azure.ADToken == "" {
+			return fmt.Errorf("API_KEY or AZURE_AD_TOKEN is required for the azure provider")
+		}
+	default:
+		return fmt.Errorf("unsupported provider %q", c.Provider)
 	}
-	if c.Prompt == "" {
+	if c.Prompt == "" && c.PromptFile == "" && c.PromptName == "" {
 		return fmt.Errorf("PROMPT is required")
 	}
+	if (c.Prompt != "" && c.PromptFile != "") || (c.Prompt != "" && c.PromptName != "") || (c.PromptFile != "" && c.PromptName != "") {
+		return fmt.Errorf("only one of PROMPT, PROMPT_FILE and PROMPT_NAME can be set")
+	}
+	switch c.Source {
+	case "", "files", "diff":
+	default:
+		return fmt.Errorf("unsupported source %q", c.Source)
+	}
+	if c.DiffContext < 0 {
+		return fmt.Errorf("DIFF_CONTEXT must not be negative")
+	}
+	if c.Gate && c.ResponseSchema != "" {
+		return fmt.Errorf("RESPONSE_SCHEMA cannot be combined with GATE")
+	}
+	if c.Retry.MaxRetries < 0 {
+		return fmt.Errorf("MAX_RETRIES must not be negative")
+	}
+	if c.MaxConcurrency < 0 {
+		return fmt.Errorf("MAX_CONCURRENCY must not be negative")
+	}
+	switch c.Publish.Target {
+	case "":
+	case "auto", "github", "gitlab", "gitea", "forgejo":
+		if c.Publish.Token == "" {
+			return fmt.Errorf("PUBLISH_TOKEN is required to publish to %s", c.Publish.Target)
+		}
+	default:
+		return fmt.Errorf("unsupported publish target %q", c.Publish.Target)
+	}
+	if c.OutputFormat == "sarif" && !c.Gate {
+		return fmt.Errorf("OUTPUT_FORMAT sarif requires GATE")
+	}
+	if c.Publish.Review && !c.Gate {
+		return fmt.Errorf("PUBLISH_REVIEW requires GATE")
+	}
+	if c.Agent {
+		switch c.Provider {
+		case "", "openai", "azure":
+		default:
+			return fmt.Errorf("AGENT is not supported by the %s provider", c.Provider)
+		}
+		if c.MaxIterations < 1 {
+			return fmt.Errorf("MAX_ITERATIONS must be at least 1")
+		}
+		if c.BudgetPolicy == "map_reduce" {
+			return fmt.Errorf("AGENT cannot be combined with BUDGET_POLICY map_reduce")
+		}
+	}
 	return nil
 }
 
+// RequestModel returns the model name sent to the provider. Azure routes
+// requests by deployment, so the deployment name is used when set.
+func (c *Config) RequestModel() string {
+	if c.Provider == "azure" && c.Azure.Deployment != "" {
+		return c.Azure.Deployment
+	}
+	return c.Model
+}
+
+// IsSet reports whether a setting is given explicitly in the environment,
+// as opposed to taking its default value
+func IsSet(name string) bool {
+	_, ok := os.LookupEnv("PLUGIN_" + name)
+	return ok
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -61,6 +283,29 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
+// getEnvDuration parses a Go duration such as "500ms"; a plain number is
+// treated as seconds
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+			return time.Duration(seconds * float64(time.Second))
+		}
+	}
+	return defaultValue
+}
+
 func getEnvFloat(key string, defaultValue float64) float64 {
 	if value := os.Getenv(key); value != "" {
 		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
@@ -70,3 +315,37 @@ func getEnvFloat(key string, defaultValue float64) float64 {
 	return defaultValue
 }
 
+// getEnvSlice parses a list setting. Drone passes list settings as a comma
+// separated string.
+func getEnvSlice(key string) []string {
+	var result []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// getEnvMap parses a map setting. Drone passes map settings as a JSON object;
+// a comma separated list of key=value pairs is accepted as well.
+func getEnvMap(key string) map[string]string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	result := map[string]string{}
+	if err := json.Unmarshal([]byte(value), &result); err == nil {
+		return result
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		result[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return result
+}
@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -26,6 +28,7 @@ func TestLoad(t *testing.T) {
 				MaxTokens:    1000,
 				SystemPrompt: "You are a helpful assistant.",
 				OutputFile:   "",
+				OutputFormat: "text",
 				Timeout:      60,
 			},
 		},
@@ -40,7 +43,9 @@ func TestLoad(t *testing.T) {
 				"PLUGIN_MAX_TOKENS":    "2000",
 				"PLUGIN_SYSTEM_PROMPT": "Custom system prompt",
 				"PLUGIN_OUTPUT_FILE":   "output.txt",
+				"PLUGIN_OUTPUT_FORMAT": "json",
 				"PLUGIN_TIMEOUT":       "120",
+				"PLUGIN_STREAM":        "true",
 			},
 			expected: Config{
 				APIKey:       "custom-key",
@@ -51,7 +56,9 @@ func TestLoad(t *testing.T) {
 				MaxTokens:    2000,
 				SystemPrompt: "Custom system prompt",
 				OutputFile:   "output.txt",
+				OutputFormat: "json",
 				Timeout:      120,
+				Stream:       true,
 			},
 		},
 		{
@@ -70,6 +77,7 @@ func TestLoad(t *testing.T) {
 				Temperature:  0.7,
 				MaxTokens:    1000,
 				SystemPrompt: "You are a helpful assistant.",
+				OutputFormat: "text",
 				Timeout:      60,
 			},
 		},
@@ -90,6 +98,9 @@ func TestLoad(t *testing.T) {
 			cfg := Load()
 
 			// Validate fields
+			if cfg.Provider != "openai" {
+				t.Errorf("Provider = %v, want openai", cfg.Provider)
+			}
 			if cfg.APIKey != tt.expected.APIKey {
 				t.Errorf("APIKey = %v, want %v", cfg.APIKey, tt.expected.APIKey)
 			}
@@ -114,9 +125,15 @@ func TestLoad(t *testing.T) {
 			if cfg.OutputFile != tt.expected.OutputFile {
 				t.Errorf("OutputFile = %v, want %v", cfg.OutputFile, tt.expected.OutputFile)
 			}
+			if cfg.OutputFormat != tt.expected.OutputFormat {
+				t.Errorf("OutputFormat = %v, want %v", cfg.OutputFormat, tt.expected.OutputFormat)
+			}
 			if cfg.Timeout != tt.expected.Timeout {
 				t.Errorf("Timeout = %v, want %v", cfg.Timeout, tt.expected.Timeout)
 			}
+			if cfg.Stream != tt.expected.Stream {
+				t.Errorf("Stream = %v, want %v", cfg.Stream, tt.expected.Stream)
+			}
 		})
 	}
 }
@@ -154,6 +171,182 @@ func TestValidate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "PROMPT is required",
 		},
+		{
+			name: "unsupported provider",
+			config: Config{
+				Provider: "unknown",
+				APIKey:   "test-key",
+				Prompt:   "test prompt",
+			},
+			wantErr: true,
+			errMsg:  `unsupported provider "unknown"`,
+		},
+		{
+			name: "anthropic missing API key",
+			config: Config{
+				Provider: "anthropic",
+				Prompt:   "test prompt",
+			},
+			wantErr: true,
+			errMsg:  "API_KEY is required",
+		},
+		{
+			name: "gemini missing API key",
+			config: Config{
+				Provider: "gemini",
+				Prompt:   "test prompt",
+			},
+			wantErr: true,
+			errMsg:  "API_KEY is required",
+		},
+		{
+			name: "ollama without API key",
+			config: Config{
+				Provider: "ollama",
+				Prompt:   "test prompt",
+			},
+			wantErr: false,
+		},
+		{
+			name: "azure with api key",
+			config: Config{
+				Provider: "azure",
+				APIKey:   "test-key",
+				Prompt:   "test prompt",
+				Azure:    Azure{Endpoint: "https://example.openai.azure.com"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "azure with ad token",
+			config: Config{
+				Provider: "azure",
+				Prompt:   "test prompt",
+				Azure:    Azure{Endpoint: "https://example.openai.azure.com", ADToken: "token"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "azure missing endpoint",
+			config: Config{
+				Provider: "azure",
+				APIKey:   "test-key",
+				Prompt:   "test prompt",
+			},
+			wantErr: true,
+			errMsg:  "AZURE_ENDPOINT is required for the azure provider",
+		},
+		{
+			name: "azure missing credentials",
+			config: Config{
+				Provider: "azure",
+				Prompt:   "test prompt",
+				Azure:    Azure{Endpoint: "https://example.openai.azure.com"},
+			},
+			wantErr: true,
+			errMsg:  "API_KEY or AZURE_AD_TOKEN is required for the azure provider",
+		},
+		{
+			name: "negative retries",
+			config: Config{
+				APIKey: "test-key",
+				Prompt: "test prompt",
+				Retry:  Retry{MaxRetries: -1},
+			},
+			wantErr: true,
+			errMsg:  "MAX_RETRIES must not be negative",
+		},
+		{
+			name: "unsupported source",
+			config: Config{
+				APIKey: "test-key",
+				Prompt: "test prompt",
+				Source: "commits",
+			},
+			wantErr: true,
+			errMsg:  `unsupported source "commits"`,
+		},
+		{
+			name: "gate with response schema",
+			config: Config{
+				APIKey:         "test-key",
+				Prompt:         "test prompt",
+				Gate:           true,
+				ResponseSchema: `{"type": "object"}`,
+			},
+			wantErr: true,
+			errMsg:  "RESPONSE_SCHEMA cannot be combined with GATE",
+		},
+		{
+			name: "prompt from catalog",
+			config: Config{
+				APIKey:     "test-key",
+				PromptName: "review",
+			},
+			wantErr: false,
+		},
+		{
+			name: "prompt and prompt file",
+			config: Config{
+				APIKey:     "test-key",
+				Prompt:     "test prompt",
+				PromptFile: "prompt.md",
+			},
+			wantErr: true,
+			errMsg:  "only one of PROMPT, PROMPT_FILE and PROMPT_NAME can be set",
+		},
+		{
+			name: "agent with unsupported provider",
+			config: Config{
+				Provider:      "anthropic",
+				APIKey:        "test-key",
+				Prompt:        "test prompt",
+				Agent:         true,
+				MaxIterations: 10,
+			},
+			wantErr: true,
+			errMsg:  "AGENT is not supported by the anthropic provider",
+		},
+		{
+			name: "agent without iterations",
+			config: Config{
+				APIKey: "test-key",
+				Prompt: "test prompt",
+				Agent:  true,
+			},
+			wantErr: true,
+			errMsg:  "MAX_ITERATIONS must be at least 1",
+		},
+		{
+			name: "publish without token",
+			config: Config{
+				APIKey:  "test-key",
+				Prompt:  "test prompt",
+				Publish: Publish{Target: "github"},
+			},
+			wantErr: true,
+			errMsg:  "PUBLISH_TOKEN is required to publish to github",
+		},
+		{
+			name: "sarif without gate",
+			config: Config{
+				APIKey:       "test-key",
+				Prompt:       "test prompt",
+				OutputFormat: "sarif",
+			},
+			wantErr: true,
+			errMsg:  "OUTPUT_FORMAT sarif requires GATE",
+		},
+		{
+			name: "review without gate",
+			config: Config{
+				APIKey:  "test-key",
+				Prompt:  "test prompt",
+				Publish: Publish{Target: "github", Token: "ghp_test", Review: true},
+			},
+			wantErr: true,
+			errMsg:  "PUBLISH_REVIEW requires GATE",
+		},
 		{
 			name: "missing both",
 			config: Config{
@@ -183,18 +376,237 @@ func TestValidate(t *testing.T) {
 	}
 }
 
-// clearEnv clears all PLUGIN_* environment variables
+func TestLoad_BuildMetadata(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	os.Setenv("DRONE_REPO", "octocat/hello-world")
+	os.Setenv("DRONE_BRANCH", "main")
+	os.Setenv("DRONE_COMMIT_SHA", "abc123")
+	os.Setenv("DRONE_BUILD_NUMBER", "42")
+
+	cfg := Load()
+	expected := Build{
+		Repo:      "octocat/hello-world",
+		Branch:    "main",
+		CommitSHA: "abc123",
+		Number:    "42",
+	}
+	if cfg.Build != expected {
+		t.Errorf("Build = %+v, want %+v", cfg.Build, expected)
+	}
+}
+
+func TestLoad_Azure(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	os.Setenv("PLUGIN_PROVIDER", "azure")
+	os.Setenv("PLUGIN_AZURE_ENDPOINT", "https://example.openai.azure.com")
+	os.Setenv("PLUGIN_AZURE_DEPLOYMENT", "gpt4o-prod")
+
+	cfg := Load()
+	if cfg.Provider != "azure" {
+		t.Errorf("Provider = %v, want azure", cfg.Provider)
+	}
+	expected := Azure{
+		Endpoint:   "https://example.openai.azure.com",
+		Deployment: "gpt4o-prod",
+		APIVersion: "2024-10-21",
+	}
+	if cfg.Azure != expected {
+		t.Errorf("Azure = %+v, want %+v", cfg.Azure, expected)
+	}
+}
+
+func TestLoad_Retry(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	cfg := Load()
+	expected := Retry{MaxRetries: 2, Backoff: time.Second, MaxBackoff: 30 * time.Second}
+	if cfg.Retry != expected {
+		t.Errorf("default Retry = %+v, want %+v", cfg.Retry, expected)
+	}
+
+	os.Setenv("PLUGIN_MAX_RETRIES", "5")
+	os.Setenv("PLUGIN_RETRY_BACKOFF", "250ms")
+	os.Setenv("PLUGIN_RETRY_MAX_BACKOFF", "10")
+
+	cfg = Load()
+	expected = Retry{MaxRetries: 5, Backoff: 250 * time.Millisecond, MaxBackoff: 10 * time.Second}
+	if cfg.Retry != expected {
+		t.Errorf("Retry = %+v, want %+v", cfg.Retry, expected)
+	}
+}
+
+func TestLoad_Publish(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	if cfg := Load(); cfg.Publish != (Publish{Key: "review"}) {
+		t.Errorf("default Publish = %+v", cfg.Publish)
+	}
+
+	os.Setenv("PLUGIN_PUBLISH", "github")
+	os.Setenv("PLUGIN_PUBLISH_TOKEN", "ghp_test")
+	os.Setenv("PLUGIN_PUBLISH_API_URL", "https://ghe.example.com/api/v3")
+	os.Setenv("DRONE_STEP_NAME", "security")
+
+	cfg := Load()
+	expected := Publish{Target: "github", Token: "ghp_test", APIURL: "https://ghe.example.com/api/v3", Key: "security"}
+	if cfg.Publish != expected {
+		t.Errorf("Publish = %+v, want %+v", cfg.Publish, expected)
+	}
+}
+
+func TestLoad_FallbackModels(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	os.Setenv("PLUGIN_FALLBACK_MODELS", "gpt-4o, gpt-4.1-mini,")
+
+	cfg := Load()
+	expected := []string{"gpt-4o", "gpt-4.1-mini"}
+	if len(cfg.FallbackModels) != len(expected) {
+		t.Fatalf("FallbackModels = %v, want %v", cfg.FallbackModels, expected)
+	}
+	for i := range expected {
+		if cfg.FallbackModels[i] != expected[i] {
+			t.Errorf("FallbackModels = %v, want %v", cfg.FallbackModels, expected)
+		}
+	}
+}
+
+func TestLoad_Files(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	os.Setenv("PLUGIN_FILES", "internal/**/*.go,docs/*.png")
+
+	cfg := Load()
+	expected := []string{"internal/**/*.go", "docs/*.png"}
+	if strings.Join(cfg.Files, ",") != strings.Join(expected, ",") {
+		t.Errorf("Files = %v, want %v", cfg.Files, expected)
+	}
+}
+
+func TestRequestModel(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		expected string
+	}{
+		{"openai uses model", Config{Provider: "openai", Model: "gpt-4o"}, "gpt-4o"},
+		{"azure uses deployment", Config{Provider: "azure", Model: "gpt-4o", Azure: Azure{Deployment: "prod-gpt4o"}}, "prod-gpt4o"},
+		{"azure without deployment uses model", Config{Provider: "azure", Model: "gpt-4o"}, "gpt-4o"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.RequestModel(); got != tt.expected {
+				t.Errorf("RequestModel() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetEnvMap(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected map[string]string
+	}{
+		{"unset", "", nil},
+		{"json object", `{"X-Team":"platform","X-Env":"ci"}`, map[string]string{"X-Team": "platform", "X-Env": "ci"}},
+		{"key value pairs", "X-Team=platform, X-Env=ci", map[string]string{"X-Team": "platform", "X-Env": "ci"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("PLUGIN_EXTRA_HEADERS", tt.value)
+			defer os.Unsetenv("PLUGIN_EXTRA_HEADERS")
+
+			result := getEnvMap("PLUGIN_EXTRA_HEADERS")
+			if len(result) != len(tt.expected) {
+				t.Fatalf("getEnvMap() = %v, want %v", result, tt.expected)
+			}
+			for key, want := range tt.expected {
+				if result[key] != want {
+					t.Errorf("getEnvMap()[%q] = %q, want %q", key, result[key], want)
+				}
+			}
+		})
+	}
+}
+
+// clearEnv clears all PLUGIN_* and build metadata environment variables
 func clearEnv() {
 	envVars := []string{
+		"PLUGIN_PROVIDER",
 		"PLUGIN_API_KEY",
+		"PLUGIN_BASE_URL",
+		"PLUGIN_ORGANIZATION",
+		"PLUGIN_PROJECT",
+		"PLUGIN_EXTRA_HEADERS",
 		"PLUGIN_MODEL",
+		"PLUGIN_FALLBACK_MODELS",
 		"PLUGIN_PROMPT",
+		"PLUGIN_PROMPT_FILE",
+		"PLUGIN_PROMPT_NAME",
+		"PLUGIN_PROMPT_DIR",
 		"PLUGIN_FILE",
+		"PLUGIN_FILES",
+		"PLUGIN_SOURCE",
+		"PLUGIN_DIFF_CONTEXT",
+		"PLUGIN_MAX_INPUT_TOKENS",
+		"PLUGIN_BUDGET_POLICY",
+		"PLUGIN_TOKENIZER_DIR",
+		"PLUGIN_MAX_CONCURRENCY",
+		"PLUGIN_AGENT",
+		"PLUGIN_PUBLISH",
+		"PLUGIN_PUBLISH_TOKEN",
+		"PLUGIN_PUBLISH_API_URL",
+		"PLUGIN_PUBLISH_KEY",
+		"PLUGIN_PUBLISH_REVIEW",
+		"PLUGIN_MAX_ITERATIONS",
 		"PLUGIN_TEMPERATURE",
 		"PLUGIN_MAX_TOKENS",
 		"PLUGIN_SYSTEM_PROMPT",
+		"PLUGIN_SYSTEM_PROMPT_FILE",
+		"PLUGIN_MESSAGES",
+		"PLUGIN_TEMPLATE",
+		"PLUGIN_TEMPLATE_ENV",
+		"PLUGIN_RESPONSE_SCHEMA",
+		"PLUGIN_GATE",
+		"PLUGIN_FAIL_ON",
 		"PLUGIN_OUTPUT_FILE",
+		"PLUGIN_OUTPUT_FORMAT",
 		"PLUGIN_TIMEOUT",
+		"PLUGIN_STREAM",
+		"PLUGIN_MAX_RETRIES",
+		"PLUGIN_RETRY_BACKOFF",
+		"PLUGIN_RETRY_MAX_BACKOFF",
+		"PLUGIN_AZURE_ENDPOINT",
+		"PLUGIN_AZURE_DEPLOYMENT",
+		"PLUGIN_AZURE_API_VERSION",
+		"PLUGIN_AZURE_AD_TOKEN",
+		"DRONE_REPO",
+		"DRONE_BRANCH",
+		"DRONE_COMMIT_SHA",
+		"DRONE_BUILD_NUMBER",
+		"DRONE_COMMIT_BEFORE",
+		"DRONE_COMMIT_AFTER",
+		"DRONE_COMMIT_MESSAGE",
+		"DRONE_COMMIT_AUTHOR",
+		"DRONE_BUILD_EVENT",
+		"DRONE_PULL_REQUEST_TITLE",
+		"DRONE_TARGET_BRANCH",
+		"DRONE_PULL_REQUEST",
+		"DRONE_WORKSPACE",
+		"DRONE_STEP_NAME",
+		"DRONE_REPO_LINK",
+		"DRONE_SYSTEM_HOST",
 	}
 	for _, key := range envVars {
 		os.Unsetenv(key)
@@ -0,0 +1,154 @@
+package diff
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+)
+
+// zeroSHA is sent as the before commit when a branch is first pushed
+const zeroSHA = "0000000000000000000000000000000000000000"
+
+// Options selects the commits to compare and how the diff is produced
+type Options struct {
+	Dir          string   // repository working tree, the current directory when empty
+	Before       string   // base commit of a push
+	After        string   // head commit, HEAD when empty
+	TargetBranch string   // pull request target branch, takes precedence over Before, empty for pushes
+	Context      int      // lines of context around each change
+	Paths        []string // optional glob patterns limiting the diff
+}
+
+// Diff is a unified diff between two revisions
+type Diff struct {
+	Base  string
+	Head  string
+	Patch string
+}
+
+// Range returns the compared revisions in git notation
+func (d *Diff) Range() string {
+	return d.Base + "..." + d.Head
+}
+
+// Empty reports whether the revisions have no changes
+func (d *Diff) Empty() bool {
+	return strings.TrimSpace(d.Patch) == ""
+}
+
+// FilePatch is the part of a diff that changes a single file
+type FilePatch struct {
+	Path  string
+	Patch string
+}
+
+// Files splits the patch into one part per changed file
+func (d *Diff) Files() []FilePatch {
+	var files []FilePatch
+	rest := d.Patch
+	for rest != "" {
+		end := len(rest)
+		if i := strings.Index(rest, "\ndiff --git "); i >= 0 {
+			end = i + 1
+		}
+		header, _, _ := strings.Cut(rest[:end], "\n")
+		files = append(files, FilePatch{Path: patchPath(header), Patch: rest[:end]})
+		rest = rest[end:]
+	}
+	return files
+}
+
+// Header returns the lines before the first hunk, from "diff --git" to the
+// "+++" line, so a hunk split off from the patch can be tied to its file
+func (f FilePatch) Header() string {
+	if i := strings.Index(f.Patch, "\n@@ "); i >= 0 {
+		return f.Patch[:i+1]
+	}
+	return f.Patch
+}
+
+// patchPath returns the new path from a "diff --git a/<old> b/<new>" header
+func patchPath(header string) string {
+	header = strings.TrimSpace(strings.TrimPrefix(header, "diff --git "))
+	if i := strings.LastIndex(header, " b/"); i >= 0 {
+		return header[i+3:]
+	}
+	return header
+}
+
+// Compute shells out to git to produce the unified diff for the options.
+// Pull requests are compared against the merge base with the target branch,
+// pushes against the commit before the push.
+func Compute(ctx context.Context, opts Options, logger *slog.Logger) (*Diff, error) {
+	head := opts.After
+	if head == "" {
+		head = "HEAD"
+	}
+
+	base, err := resolveBase(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"diff", "--no-color", "--no-ext-diff", fmt.Sprintf("--unified=%d", opts.Context), base + "..." + head}
+	if len(opts.Paths) > 0 {
+		args = append(args, "--")
+		for _, path := range opts.Paths {
+			args = append(args, ":(glob)"+path)
+		}
+	}
+
+	logger.Info("computing git diff", "base", base, "head", head, "context", opts.Context, "paths", opts.Paths)
+	patch, err := git(ctx, opts.Dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &Diff{Base: base, Head: head, Patch: patch}, nil
+}
+
+// resolveBase picks the base revision for the diff. The remote tracking
+// branch is preferred for pull requests since CI clones often have no local
+// branch for the target.
+func resolveBase(ctx context.Context, opts Options) (string, error) {
+	if opts.TargetBranch != "" {
+		for _, ref := range []string{"origin/" + opts.TargetBranch, opts.TargetBranch} {
+			if _, err := git(ctx, opts.Dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err == nil {
+				return ref, nil
+			}
+		}
+		return "", fmt.Errorf("target branch %q not found, fetch it before the step runs", opts.TargetBranch)
+	}
+	if opts.Before == "" || opts.Before == zeroSHA {
+		return "", fmt.Errorf("no base commit to diff against, set DRONE_COMMIT_BEFORE or DRONE_TARGET_BRANCH")
+	}
+	return opts.Before, nil
+}
+
+// git runs a git command in dir and returns its standard output
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return "", fmt.Errorf("git %s: %w", args[0], err)
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return stdout.String(), nil
+}
+
+// Prompt appends the diff to the prompt, delimited by the compared range
+func Prompt(prompt string, d *Diff) string {
+	patch := d.Patch
+	if !strings.HasSuffix(patch, "\n") {
+		patch += "\n"
+	}
+	return fmt.Sprintf("%s\n\n<diff range=%q>\n%s</diff>", prompt, d.Range(), patch)
+}
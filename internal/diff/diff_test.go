@@ -0,0 +1,170 @@
+package diff
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testRepo creates a git repository with a main branch and a feature branch
+// that changes one Go file and one markdown file. It returns the repository
+// directory and the commits before and after the feature change.
+func testRepo(t *testing.T) (dir, before, after string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir = t.TempDir()
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	run("init", "--quiet", "--initial-branch=main")
+	write("main.go", "package main\n\nfunc main() {\n\tprintln(\"one\")\n}\n")
+	write("README.md", "# test\n")
+	run("add", "-A")
+	run("commit", "--quiet", "-m", "initial")
+	before = run("rev-parse", "HEAD")
+
+	run("checkout", "--quiet", "-b", "feature")
+	write("main.go", "package main\n\nfunc main() {\n\tprintln(\"two\")\n}\n")
+	write("README.md", "# test\n\nMore docs.\n")
+	run("commit", "--quiet", "-am", "change")
+	after = run("rev-parse", "HEAD")
+	return dir, before, after
+}
+
+func TestCompute(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dir, before, after := testRepo(t)
+
+	tests := []struct {
+		name     string
+		opts     Options
+		contains []string
+		excludes []string
+		wantErr  bool
+	}{
+		{
+			name:     "push range",
+			opts:     Options{Dir: dir, Before: before, After: after, Context: 3},
+			contains: []string{"diff --git a/main.go b/main.go", "-\tprintln(\"one\")", "+\tprintln(\"two\")", "README.md"},
+		},
+		{
+			name:     "pull request target branch",
+			opts:     Options{Dir: dir, TargetBranch: "main", Context: 3},
+			contains: []string{"+\tprintln(\"two\")"},
+		},
+		{
+			name:     "no context lines",
+			opts:     Options{Dir: dir, Before: before, After: after, Context: 0},
+			contains: []string{"+\tprintln(\"two\")"},
+			excludes: []string{"\n func main() {"},
+		},
+		{
+			name:     "limited to paths",
+			opts:     Options{Dir: dir, Before: before, After: after, Context: 3, Paths: []string{"**/*.go"}},
+			contains: []string{"main.go"},
+			excludes: []string{"README.md"},
+		},
+		{
+			name:    "missing base",
+			opts:    Options{Dir: dir, Before: zeroSHA, After: after},
+			wantErr: true,
+		},
+		{
+			name:    "unknown target branch",
+			opts:    Options{Dir: dir, TargetBranch: "develop"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Compute(context.Background(), tt.opts, logger)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Compute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(d.Patch, want) {
+					t.Errorf("Patch should contain %q, got:\n%s", want, d.Patch)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(d.Patch, unwanted) {
+					t.Errorf("Patch should not contain %q, got:\n%s", unwanted, d.Patch)
+				}
+			}
+		})
+	}
+}
+
+func TestCompute_Empty(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dir, _, after := testRepo(t)
+
+	d, err := Compute(context.Background(), Options{Dir: dir, Before: after, After: after}, logger)
+	if err != nil {
+		t.Fatalf("Compute() error = %v", err)
+	}
+	if !d.Empty() {
+		t.Errorf("Expected empty diff, got:\n%s", d.Patch)
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	d := &Diff{Base: "main", Head: "HEAD", Patch: "+added"}
+
+	expected := "Review this change\n\n<diff range=\"main...HEAD\">\n+added\n</diff>"
+	if got := Prompt("Review this change", d); got != expected {
+		t.Errorf("Prompt() = %q, want %q", got, expected)
+	}
+}
+
+func TestDiff_Files(t *testing.T) {
+	d := &Diff{Patch: "diff --git a/a.go b/a.go\n+a\ndiff --git a/old.go b/new.go\n+b\n"}
+
+	files := d.Files()
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 files, got %d", len(files))
+	}
+	if files[0].Path != "a.go" || files[0].Patch != "diff --git a/a.go b/a.go\n+a\n" {
+		t.Errorf("First file = %+v", files[0])
+	}
+	if files[1].Path != "new.go" || files[1].Patch != "diff --git a/old.go b/new.go\n+b\n" {
+		t.Errorf("Second file = %+v", files[1])
+	}
+}
+
+func TestFilePatch_Header(t *testing.T) {
+	f := FilePatch{Path: "a.go", Patch: "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-a\n+b\n"}
+	if got := f.Header(); got != "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n" {
+		t.Errorf("Header() = %q", got)
+	}
+
+	binary := FilePatch{Path: "logo.png", Patch: "diff --git a/logo.png b/logo.png\nBinary files differ\n"}
+	if got := binary.Header(); got != binary.Patch {
+		t.Errorf("Header() of a patch without hunks = %q", got)
+	}
+}
@@ -0,0 +1,39 @@
+package diff
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hunkHeader matches "@@ -a,b +c,d @@" and captures the new start line
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// HunkLines returns the lines of the new version of a file that appear in
+// the hunks of its patch, as added or context lines. These are the lines a
+// pull request review can comment on.
+func HunkLines(patch string) map[int]bool {
+	lines := make(map[int]bool)
+	line := 0
+	inHunk := false
+	for _, text := range strings.Split(patch, "\n") {
+		if m := hunkHeader.FindStringSubmatch(text); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			inHunk = true
+			continue
+		}
+		if !inHunk {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(text, "+"), strings.HasPrefix(text, " "):
+			lines[line] = true
+			line++
+		case strings.HasPrefix(text, "-"), strings.HasPrefix(text, `\`):
+			// removed lines and "\ No newline at end of file" markers
+		case strings.HasPrefix(text, "diff --git "):
+			inHunk = false
+		}
+	}
+	return lines
+}
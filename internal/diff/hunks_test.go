@@ -0,0 +1,44 @@
+package diff
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestHunkLines(t *testing.T) {
+	patch := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,5 @@
+ package main
+-import "fmt"
++import (
++	"fmt"
++)
+ 
+@@ -20,3 +21,2 @@ func main() {
+ 	fmt.Println("a")
+-	fmt.Println("b")
+ }
+\ No newline at end of file`
+
+	got := HunkLines(patch)
+	var lines []int
+	for line := range got {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+
+	want := []int{1, 2, 3, 4, 5, 21, 22}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("HunkLines() = %v, want %v", lines, want)
+	}
+}
+
+func TestHunkLines_NewFile(t *testing.T) {
+	got := HunkLines("@@ -0,0 +1,2 @@\n+one\n+two")
+	if len(got) != 2 || !got[1] || !got[2] {
+		t.Errorf("HunkLines() = %v, want lines 1 and 2", got)
+	}
+}
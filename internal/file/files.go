@@ -0,0 +1,118 @@
+package file
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/openai"
+)
+
+// Attachment is a file selected for inclusion in the prompt
+type Attachment struct {
+	Path     string
+	Data     []byte
+	MimeType string // set for images only
+}
+
+// IsImage reports whether the attachment is sent as an image part
+func (a Attachment) IsImage() bool {
+	return a.MimeType != ""
+}
+
+// Collect expands the given paths and doublestar glob patterns (for example
+// "internal/**/*.go") and reads every matching file. Files matched by more
+// than one pattern are read once, in the order they were first matched.
+func (p *Processor) Collect(patterns []string) ([]Attachment, error) {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches, err := p.expand(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				paths = append(paths, match)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no files match %s", strings.Join(patterns, ", "))
+	}
+
+	attachments := make([]Attachment, 0, len(paths))
+	for _, path := range paths {
+		attachment, err := p.Read(path)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	p.logger.Info("collected files", "patterns", patterns, "files", len(attachments))
+	return attachments, nil
+}
+
+// expand returns the files matching a single path or glob pattern. A plain
+// path must exist, while a glob that matches nothing only logs a warning.
+func (p *Processor) expand(pattern string) ([]string, error) {
+	pattern = strings.TrimSpace(pattern)
+	if !strings.ContainsAny(pattern, "*?[{") {
+		if _, err := os.Stat(pattern); err != nil {
+			return nil, fmt.Errorf("error reading file: %w", err)
+		}
+		return []string{filepath.Clean(pattern)}, nil
+	}
+
+	if !doublestar.ValidatePathPattern(pattern) {
+		return nil, fmt.Errorf("invalid glob pattern %q", pattern)
+	}
+	matches, err := doublestar.FilepathGlob(pattern, doublestar.WithFilesOnly())
+	if err != nil {
+		return nil, fmt.Errorf("error expanding %q: %w", pattern, err)
+	}
+	if len(matches) == 0 {
+		p.logger.Warn("pattern matched no files", "pattern", pattern)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// BuildMessage combines the prompt and attachments into one user message.
+// The content is plain text unless at least one attachment is an image.
+func BuildMessage(prompt string, attachments []Attachment) openai.Message {
+	var text strings.Builder
+	text.WriteString(prompt)
+	var images []Attachment
+	for _, a := range attachments {
+		if a.IsImage() {
+			images = append(images, a)
+			continue
+		}
+		fmt.Fprintf(&text, "\n\n<file path=%q>\n%s", a.Path, a.Data)
+		if len(a.Data) > 0 && a.Data[len(a.Data)-1] != '\n' {
+			text.WriteString("\n")
+		}
+		text.WriteString("</file>")
+	}
+
+	if len(images) == 0 {
+		return openai.Message{Role: "user", Content: text.String()}
+	}
+
+	parts := []openai.MessagePart{{Type: "text", Text: text.String()}}
+	for _, img := range images {
+		dataURL := fmt.Sprintf("data:%s;base64,%s", img.MimeType, base64.StdEncoding.EncodeToString(img.Data))
+		parts = append(parts,
+			openai.MessagePart{Type: "text", Text: fmt.Sprintf("Image: %s", img.Path)},
+			openai.MessagePart{Type: "image_url", ImageURL: &openai.ImageURL{URL: dataURL}},
+		)
+	}
+	return openai.Message{Role: "user", Content: parts}
+}
@@ -0,0 +1,131 @@
+package file
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/openai"
+)
+
+// writeTree creates the given files below dir
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	processor := NewProcessor(logger)
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"internal/a/a.go":      "package a",
+		"internal/b/b.go":      "package b",
+		"internal/b/b_test.go": "package b",
+		"README.md":            "# readme",
+	})
+
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "doublestar glob",
+			patterns: []string{"internal/**/*.go"},
+			want:     []string{"internal/a/a.go", "internal/b/b.go", "internal/b/b_test.go"},
+		},
+		{
+			name:     "duplicates are read once",
+			patterns: []string{"README.md", "*.md", "internal/a/*.go"},
+			want:     []string{"README.md", "internal/a/a.go"},
+		},
+		{
+			name:     "missing plain path",
+			patterns: []string{"missing.go"},
+			wantErr:  true,
+		},
+		{
+			name:     "no matches",
+			patterns: []string{"**/*.py"},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid pattern",
+			patterns: []string{"internal/[a"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patterns := make([]string, len(tt.patterns))
+			for i, pattern := range tt.patterns {
+				patterns[i] = filepath.Join(dir, pattern)
+			}
+
+			attachments, err := processor.Collect(patterns)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Collect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var got []string
+			for _, a := range attachments {
+				rel, _ := filepath.Rel(dir, a.Path)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Collect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildMessage_MixedImages(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	processor := NewProcessor(logger)
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"docs/guide.md":    "# Guide",
+		"docs/diagram.png": "fake png",
+	})
+
+	attachments, err := processor.Collect([]string{filepath.Join(dir, "docs/**")})
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	msg := BuildMessage("Check the docs", attachments)
+
+	parts, ok := msg.Content.([]openai.MessagePart)
+	if !ok {
+		t.Fatalf("Expected multimodal content when an image matches, got %T", msg.Content)
+	}
+	if len(parts) != 3 {
+		t.Fatalf("Expected text, image label and image parts, got %d", len(parts))
+	}
+	if !strings.Contains(parts[0].Text, "# Guide") || strings.Contains(parts[0].Text, "fake png") {
+		t.Errorf("Text part should contain only the text files, got %q", parts[0].Text)
+	}
+	if !strings.HasSuffix(parts[1].Text, "diagram.png") {
+		t.Errorf("Image label = %q, want image path", parts[1].Text)
+	}
+	if parts[2].ImageURL == nil || !strings.HasPrefix(parts[2].ImageURL.URL, "data:image/png;base64,") {
+		t.Errorf("Expected PNG data URL, got %+v", parts[2].ImageURL)
+	}
+}
@@ -26,26 +26,43 @@ func NewProcessor(logger *slog.Logger) *Processor {
 func (p *Processor) ProcessFileContent(prompt, filePath string) (openai.Message, error) {
 	p.logger.Info("processing file", "path", filePath)
 	
+	attachment, err := p.Read(filePath)
+	if err != nil {
+		return openai.Message{}, err
+	}
+	return p.FileMessage(prompt, attachment), nil
+}
+
+// Read reads a single file into an attachment
+func (p *Processor) Read(filePath string) (Attachment, error) {
 	fileData, err := os.ReadFile(filePath)
 	if err != nil {
-		return openai.Message{}, fmt.Errorf("error reading file: %w", err)
+		return Attachment{}, fmt.Errorf("error reading file: %w", err)
 	}
+	attachment := Attachment{Path: filePath, Data: fileData}
+	if p.isImageFile(filePath) {
+		attachment.MimeType = p.getMimeType(filePath)
+	}
+	return attachment, nil
+}
 
+// FileMessage builds the user message for a single attachment
+func (p *Processor) FileMessage(prompt string, attachment Attachment) openai.Message {
 	// Check if it's an image file
-	if p.isImageFile(filePath) {
-		p.logger.Info("detected image file", "type", p.getMimeType(filePath))
-		return p.createImageMessage(prompt, filePath, fileData), nil
+	if attachment.IsImage() {
+		p.logger.Info("detected image file", "type", attachment.MimeType)
+		return p.createImageMessage(prompt, attachment.Path, attachment.Data)
 	}
 
 	// For text files, append content to prompt
-	p.logger.Info("detected text file", "size_bytes", len(fileData))
-	fileContent := string(fileData)
+	p.logger.Info("detected text file", "size_bytes", len(attachment.Data))
+	fileContent := string(attachment.Data)
 	combinedPrompt := fmt.Sprintf("%s\n\nFile content:\n%s", prompt, fileContent)
 	
 	return openai.Message{
 		Role:    "user",
 		Content: combinedPrompt,
-	}, nil
+	}
 }
 
 // createImageMessage creates a multimodal message for image files
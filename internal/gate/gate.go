@@ -0,0 +1,126 @@
+package gate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/schema"
+)
+
+// Severity ranks how serious a finding is
+type Severity int
+
+// Severity levels in increasing order
+const (
+	SeverityInfo Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+var severityNames = []string{"info", "low", "medium", "high", "critical"}
+
+// String returns the lowercase name of the severity
+func (s Severity) String() string {
+	if s < SeverityInfo || s > SeverityCritical {
+		return "unknown"
+	}
+	return severityNames[s]
+}
+
+// ParseSeverity converts a severity name into a Severity
+func ParseSeverity(name string) (Severity, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i, n := range severityNames {
+		if n == name {
+			return Severity(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q (expected one of %s)", name, strings.Join(severityNames, ", "))
+}
+
+// Finding is a single issue reported by the model. Rule identifies the kind
+// of issue, e.g. "sql-injection", so reports can group findings.
+type Finding struct {
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+	Path     string `json:"path"`
+	Line     int    `json:"line"`
+	EndLine  int    `json:"end_line"`
+}
+
+// Verdict is the structured answer the model gives in gate mode
+type Verdict struct {
+	Verdict  string    `json:"verdict"`
+	Summary  string    `json:"summary"`
+	Findings []Finding `json:"findings"`
+}
+
+// verdictSchema is the strict-mode JSON Schema the model must answer with
+const verdictSchema = `{
+	"title": "verdict",
+	"type": "object",
+	"properties": {
+		"verdict": {"type": "string", "enum": ["pass", "warn", "fail"]},
+		"summary": {"type": "string"},
+		"findings": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {
+					"severity": {"type": "string", "enum": ["info", "low", "medium", "high", "critical"]},
+					"rule": {"type": ["string", "null"]},
+					"title": {"type": "string"},
+					"message": {"type": "string"},
+					"path": {"type": ["string", "null"]},
+					"line": {"type": ["integer", "null"]},
+					"end_line": {"type": ["integer", "null"]}
+				},
+				"required": ["severity", "rule", "title", "message", "path", "line", "end_line"],
+				"additionalProperties": false
+			}
+		}
+	},
+	"required": ["verdict", "summary", "findings"],
+	"additionalProperties": false
+}`
+
+// Instructions is appended to the system prompt in gate mode
+const Instructions = `Answer with a verdict: "pass" when there are no problems, "warn" for minor problems and "fail" for problems that must be fixed before merging. Report every problem as a finding with a severity of info, low, medium, high or critical, a rule id naming the kind of problem in kebab-case (such as sql-injection or missing-error-check), a short title and a message explaining the problem and how to fix it. Set path to the file relative to the repository root, line to the first and end_line to the last line number of the problem in the new version of the file when the location is known, otherwise null.`
+
+// Schema returns the compiled verdict schema
+func Schema() *schema.Schema {
+	s, err := schema.Parse("verdict", []byte(verdictSchema))
+	if err != nil {
+		panic(fmt.Sprintf("invalid verdict schema: %v", err))
+	}
+	return s
+}
+
+// Parse decodes a verdict from a response validated against Schema
+func Parse(content string) (*Verdict, error) {
+	var v Verdict
+	if err := json.Unmarshal([]byte(content), &v); err != nil {
+		return nil, fmt.Errorf("error decoding verdict: %w", err)
+	}
+	return &v, nil
+}
+
+// Blocking returns the findings at or above the failOn severity
+func (v *Verdict) Blocking(failOn Severity) []Finding {
+	var blocking []Finding
+	for _, f := range v.Findings {
+		severity, err := ParseSeverity(f.Severity)
+		if err != nil {
+			continue
+		}
+		if severity >= failOn {
+			blocking = append(blocking, f)
+		}
+	}
+	return blocking
+}
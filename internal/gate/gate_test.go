@@ -0,0 +1,93 @@
+package gate
+
+import "testing"
+
+func TestParseSeverity(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Severity
+		wantErr bool
+	}{
+		{"info", SeverityInfo, false},
+		{"LOW", SeverityLow, false},
+		{" medium ", SeverityMedium, false},
+		{"high", SeverityHigh, false},
+		{"critical", SeverityCritical, false},
+		{"blocker", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSeverity(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSeverity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSeverity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchemaAndParse(t *testing.T) {
+	content := `{
+		"verdict": "fail",
+		"summary": "One injection issue",
+		"findings": [
+			{"severity": "critical", "rule": "sql-injection", "title": "SQL injection", "message": "Use parameters", "path": "db.go", "line": 42, "end_line": 44},
+			{"severity": "low", "rule": null, "title": "Naming", "message": "Rename x", "path": null, "line": null, "end_line": null}
+		]
+	}`
+
+	validated, err := Schema().Validate(content)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	v, err := Parse(validated)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if v.Verdict != "fail" || len(v.Findings) != 2 {
+		t.Fatalf("Verdict = %+v, want fail with 2 findings", v)
+	}
+	if f := v.Findings[0]; f.Path != "db.go" || f.Line != 42 || f.EndLine != 44 || f.Rule != "sql-injection" {
+		t.Errorf("Finding = %+v, want sql-injection at db.go:42-44", f)
+	}
+	if v.Findings[1].Path != "" || v.Findings[1].Line != 0 {
+		t.Errorf("Null location should decode to zero values, got %s:%d", v.Findings[1].Path, v.Findings[1].Line)
+	}
+
+	if _, err := Schema().Validate(`{"verdict": "ok", "summary": "", "findings": []}`); err == nil {
+		t.Error("Expected schema error for unknown verdict, got nil")
+	}
+}
+
+func TestBlocking(t *testing.T) {
+	v := &Verdict{
+		Verdict: "fail",
+		Findings: []Finding{
+			{Severity: "critical", Title: "a"},
+			{Severity: "high", Title: "b"},
+			{Severity: "medium", Title: "c"},
+			{Severity: "info", Title: "d"},
+		},
+	}
+
+	tests := []struct {
+		failOn Severity
+		want   int
+	}{
+		{SeverityCritical, 1},
+		{SeverityHigh, 2},
+		{SeverityMedium, 3},
+		{SeverityInfo, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.failOn.String(), func(t *testing.T) {
+			if got := len(v.Blocking(tt.failOn)); got != tt.want {
+				t.Errorf("Blocking(%s) = %d findings, want %d", tt.failOn, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/openai"
+)
+
+// defaultBaseURL is the Gemini API. Vertex AI users point BaseURL at
+// .../v1/projects/{project}/locations/{location}/publishers/google instead.
+const defaultBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// Options holds the connection settings for the Gemini API
+type Options struct {
+	APIKey  string
+	BaseURL string
+	Headers map[string]string
+}
+
+// Client calls the Gemini generateContent API
+type Client struct {
+	opts       Options
+	httpClient *http.Client
+	logger     *slog.Logger
+}
+
+// NewClient creates a new Gemini client
+func NewClient(opts Options, logger *slog.Logger) *Client {
+	if opts.BaseURL == "" {
+		opts.BaseURL = defaultBaseURL
+	}
+	opts.BaseURL = strings.TrimSuffix(opts.BaseURL, "/")
+	return &Client{
+		opts:       opts,
+		httpClient: http.DefaultClient,
+		logger:     logger,
+	}
+}
+
+// generateRequest is the body of a generateContent request
+type generateRequest struct {
+	SystemInstruction *content          `json:"systemInstruction,omitempty"`
+	Contents          []content         `json:"contents"`
+	GenerationConfig  *generationConfig `json:"generationConfig,omitempty"`
+}
+
+// content is a single conversation turn
+type content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []part `json:"parts"`
+}
+
+// part is a text or inline data part within a turn
+type part struct {
+	Text       string      `json:"text,omitempty"`
+	InlineData *inlineData `json:"inlineData,omitempty"`
+	FileData   *fileData   `json:"fileData,omitempty"`
+}
+
+// inlineData holds base64 encoded media
+type inlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+// fileData references media by URI
+type fileData struct {
+	FileURI string `json:"fileUri"`
+}
+
+// generationConfig holds the sampling parameters
+type generationConfig struct {
+	Temperature        *float64       `json:"temperature,omitempty"`
+	MaxOutputTokens    int64          `json:"maxOutputTokens,omitempty"`
+	ResponseMimeType   string         `json:"responseMimeType,omitempty"`
+	ResponseJSONSchema map[string]any `json:"responseJsonSchema,omitempty"`
+}
+
+// generateResponse is the body of a successful generateContent response
+type generateResponse struct {
+	Candidates []struct {
+		Content      content `json:"content"`
+		FinishReason string  `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int64 `json:"promptTokenCount"`
+		CandidatesTokenCount int64 `json:"candidatesTokenCount"`
+		TotalTokenCount      int64 `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+	ModelVersion string `json:"modelVersion"`
+}
+
+// errorResponse is the body returned when a request fails
+type errorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// CreateChatCompletion sends a request to generateContent and returns the response
+func (c *Client) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (*openai.ChatCompletionResponse, error) {
+	c.logger.Info("creating gemini content",
+		"model", req.Model,
+		"temperature", req.Temperature,
+		"max_tokens", req.MaxTokens,
+		"num_messages", len(req.Messages),
+	)
+
+	payload, err := json.Marshal(buildRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("error encoding request: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/models/%s:generateContent", c.opts.BaseURL, url.PathEscape(req.Model))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", c.opts.APIKey)
+	for key, value := range c.opts.Headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		c.logger.Error("Gemini API call failed", "error", err)
+		return nil, fmt.Errorf("Gemini API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr errorResponse
+		message := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			message = apiErr.Error.Status + ": " + apiErr.Error.Message
+		}
+		c.logger.Error("Gemini API call failed", "status", resp.StatusCode, "error", message)
+		return nil, &openai.APIError{
+			Provider:   "Gemini",
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+			Message:    message,
+		}
+	}
+
+	var result generateResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	if len(result.Candidates) == 0 {
+		c.logger.Error("no candidates in response")
+		return nil, fmt.Errorf("no response from Gemini")
+	}
+
+	candidate := result.Candidates[0]
+	var text strings.Builder
+	for _, p := range candidate.Content.Parts {
+		text.WriteString(p.Text)
+	}
+	if text.Len() == 0 {
+		c.logger.Warn("empty content in response", "finish_reason", candidate.FinishReason)
+		return nil, fmt.Errorf("empty response from Gemini (finish reason %s)", candidate.FinishReason)
+	}
+
+	usage := openai.Usage{
+		PromptTokens:     result.UsageMetadata.PromptTokenCount,
+		CompletionTokens: result.UsageMetadata.CandidatesTokenCount,
+		TotalTokens:      result.UsageMetadata.TotalTokenCount,
+	}
+	c.logger.Info("gemini content successful",
+		"prompt_tokens", usage.PromptTokens,
+		"completion_tokens", usage.CompletionTokens,
+		"total_tokens", usage.TotalTokens,
+		"finish_reason", candidate.FinishReason,
+	)
+
+	model := result.ModelVersion
+	if model == "" {
+		model = req.Model
+	}
+	return &openai.ChatCompletionResponse{
+		Content:      text.String(),
+		Model:        model,
+		FinishReason: candidate.FinishReason,
+		Usage:        usage,
+	}, nil
+}
+
+// buildRequest converts a chat completion request into a generateContent
+// request. System messages become the system instruction and assistant
+// messages use the "model" role.
+func buildRequest(req openai.ChatCompletionRequest) generateRequest {
+	body := generateRequest{}
+
+	config := &generationConfig{MaxOutputTokens: req.MaxTokens}
+	if req.Temperature > 0 {
+		temperature := req.Temperature
+		config.Temperature = &temperature
+	}
+	if req.ResponseSchema != nil {
+		config.ResponseMimeType = "application/json"
+		config.ResponseJSONSchema = req.ResponseSchema.Schema
+	}
+	if config.Temperature != nil || config.MaxOutputTokens > 0 || config.ResponseMimeType != "" {
+		body.GenerationConfig = config
+	}
+
+	for _, msg := range req.Messages {
+		parts := convertParts(msg.Content)
+		switch msg.Role {
+		case "system":
+			if body.SystemInstruction == nil {
+				body.SystemInstruction = &content{}
+			}
+			body.SystemInstruction.Parts = append(body.SystemInstruction.Parts, parts...)
+		case "assistant":
+			body.Contents = append(body.Contents, content{Role: "model", Parts: parts})
+		default:
+			body.Contents = append(body.Contents, content{Role: "user", Parts: parts})
+		}
+	}
+	return body
+}
+
+// convertParts converts message content into Gemini parts. Data URL images
+// from the file processor are sent inline.
+func convertParts(msgContent interface{}) []part {
+	messageParts, ok := msgContent.([]openai.MessagePart)
+	if !ok {
+		text, _ := msgContent.(string)
+		return []part{{Text: text}}
+	}
+
+	parts := make([]part, 0, len(messageParts))
+	for _, p := range messageParts {
+		if p.Type == "text" {
+			parts = append(parts, part{Text: p.Text})
+		} else if p.Type == "image_url" && p.ImageURL != nil {
+			parts = append(parts, imagePart(p.ImageURL.URL))
+		}
+	}
+	return parts
+}
+
+// imagePart converts an image URL into an inline or file data part
+func imagePart(imageURL string) part {
+	if rest, ok := strings.CutPrefix(imageURL, "data:"); ok {
+		mimeType, data, found := strings.Cut(rest, ";base64,")
+		if found {
+			return part{InlineData: &inlineData{MimeType: mimeType, Data: data}}
+		}
+	}
+	return part{FileData: &fileData{FileURI: imageURL}}
+}
@@ -0,0 +1,122 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/openai"
+)
+
+func TestCreateChatCompletion(t *testing.T) {
+	var received generateRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-2.0-flash:generateContent" {
+			t.Errorf("Path = %q, want generateContent route", r.URL.Path)
+		}
+		if got := r.Header.Get("x-goog-api-key"); got != "test-key" {
+			t.Errorf("x-goog-api-key = %q, want test-key", got)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"candidates": [{
+				"content": {"role": "model", "parts": [{"text": "Hello "}, {"text": "from Gemini"}]},
+				"finishReason": "STOP"
+			}],
+			"usageMetadata": {"promptTokenCount": 8, "candidatesTokenCount": 4, "totalTokenCount": 12},
+			"modelVersion": "gemini-2.0-flash-001"
+		}`))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	client := NewClient(Options{APIKey: "test-key", BaseURL: server.URL}, logger)
+
+	resp, err := client.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model: "gemini-2.0-flash",
+		Messages: []openai.Message{
+			{Role: "system", Content: "You are a reviewer."},
+			{Role: "user", Content: "Hi"},
+			{Role: "assistant", Content: "Hello"},
+			{Role: "user", Content: []openai.MessagePart{
+				{Type: "text", Text: "Describe this image"},
+				{Type: "image_url", ImageURL: &openai.ImageURL{URL: "data:image/jpeg;base64,aGVsbG8="}},
+			}},
+		},
+		Temperature: 0.3,
+		MaxTokens:   256,
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion() error = %v", err)
+	}
+
+	if received.SystemInstruction == nil || received.SystemInstruction.Parts[0].Text != "You are a reviewer." {
+		t.Errorf("SystemInstruction = %+v, want system prompt", received.SystemInstruction)
+	}
+	if len(received.Contents) != 3 {
+		t.Fatalf("Expected 3 contents, got %d", len(received.Contents))
+	}
+	if received.Contents[1].Role != "model" {
+		t.Errorf("Assistant role = %q, want model", received.Contents[1].Role)
+	}
+	image := received.Contents[2].Parts[1].InlineData
+	if image == nil || image.MimeType != "image/jpeg" || image.Data != "aGVsbG8=" {
+		t.Errorf("InlineData = %+v, want base64 image/jpeg", image)
+	}
+	if received.GenerationConfig == nil || received.GenerationConfig.MaxOutputTokens != 256 {
+		t.Errorf("GenerationConfig = %+v, want maxOutputTokens 256", received.GenerationConfig)
+	}
+
+	if resp.Content != "Hello from Gemini" {
+		t.Errorf("Content = %q, want %q", resp.Content, "Hello from Gemini")
+	}
+	if resp.Model != "gemini-2.0-flash-001" {
+		t.Errorf("Model = %q, want model version", resp.Model)
+	}
+	if resp.Usage.PromptTokens != 8 || resp.Usage.CompletionTokens != 4 || resp.Usage.TotalTokens != 12 {
+		t.Errorf("Usage = %+v, want 8/4/12", resp.Usage)
+	}
+}
+
+func TestCreateChatCompletion_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error": {"code": 403, "message": "API key not valid", "status": "PERMISSION_DENIED"}}`))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	client := NewClient(Options{APIKey: "bad", BaseURL: server.URL}, logger)
+
+	_, err := client.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model:    "gemini-2.0-flash",
+		Messages: []openai.Message{{Role: "user", Content: "Hello"}},
+	})
+	if err == nil {
+		t.Fatal("Expected error for rejected key, got nil")
+	}
+}
+
+func TestBuildRequest_ResponseSchema(t *testing.T) {
+	body := buildRequest(openai.ChatCompletionRequest{
+		Model:          "gemini-2.0-flash",
+		Messages:       []openai.Message{{Role: "user", Content: "Review"}},
+		ResponseSchema: &openai.JSONSchema{Name: "verdict", Schema: map[string]any{"type": "object"}},
+	})
+	if body.GenerationConfig == nil {
+		t.Fatal("GenerationConfig should be set for structured output")
+	}
+	if body.GenerationConfig.ResponseMimeType != "application/json" {
+		t.Errorf("ResponseMimeType = %q, want application/json", body.GenerationConfig.ResponseMimeType)
+	}
+	if body.GenerationConfig.ResponseJSONSchema["type"] != "object" {
+		t.Errorf("ResponseJSONSchema = %v, want schema", body.GenerationConfig.ResponseJSONSchema)
+	}
+}
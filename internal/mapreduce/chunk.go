@@ -0,0 +1,110 @@
+package mapreduce
+
+import (
+	"strings"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/file"
+)
+
+// chunkOverhead is reserved in every chunk for file delimiters and the note
+// telling the model which part it sees
+const chunkOverhead = 32
+
+// segment is a piece of a file that is never split further
+type segment struct {
+	path   string
+	text   string
+	tokens int
+}
+
+// Split packs the text attachments into chunks that fit the runner's token
+// limit. Small files share a chunk, while larger files are split before
+// top-level declarations or, when a declaration is itself too large, at line
+// boundaries. Consecutive pieces of the same file in a chunk are joined, so
+// every chunk holds at most one attachment per file. Images are skipped.
+func (r *Runner) Split(attachments []file.Attachment) [][]file.Attachment {
+	limit := r.maxTokens - chunkOverhead
+	if limit < 1 {
+		limit = 1
+	}
+
+	var segments []segment
+	for _, a := range attachments {
+		if a.IsImage() {
+			r.logger.Warn("image skipped in map-reduce mode", "path", a.Path)
+			continue
+		}
+		for _, block := range blocks(string(a.Data)) {
+			tokens := r.counter.Count(block)
+			if tokens <= limit {
+				segments = append(segments, segment{a.Path, block, tokens})
+				continue
+			}
+			for _, line := range strings.SplitAfter(block, "\n") {
+				if line != "" {
+					segments = append(segments, segment{a.Path, line, r.counter.Count(line)})
+				}
+			}
+		}
+	}
+
+	var chunks [][]file.Attachment
+	var current []file.Attachment
+	used := 0
+	for _, s := range segments {
+		if len(current) > 0 && used+s.tokens > limit {
+			chunks = append(chunks, current)
+			current, used = nil, 0
+		}
+		if n := len(current); n > 0 && current[n-1].Path == s.path {
+			current[n-1].Data = append(current[n-1].Data, s.text...)
+		} else {
+			current = append(current, file.Attachment{Path: s.path, Data: []byte(s.text)})
+		}
+		used += s.tokens
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// blocks splits text before each top-level declaration, so functions and
+// types stay together when a file is split
+func blocks(text string) []string {
+	var result []string
+	start := 0
+	prev := ""
+	for offset := 0; offset < len(text); {
+		end := strings.IndexByte(text[offset:], '\n')
+		if end < 0 {
+			end = len(text)
+		} else {
+			end += offset + 1
+		}
+		line := text[offset:end]
+		if offset > start && isBoundary(prev, line) {
+			result = append(result, text[start:offset])
+			start = offset
+		}
+		prev = line
+		offset = end
+	}
+	if start < len(text) {
+		result = append(result, text[start:])
+	}
+	return result
+}
+
+// isBoundary reports whether a new block starts at line: an unindented line
+// after a blank line or a closing bracket, or a diff hunk header
+func isBoundary(prev, line string) bool {
+	if strings.HasPrefix(line, "@@ ") || strings.HasPrefix(line, "diff --git ") {
+		return true
+	}
+	if strings.TrimSpace(line) == "" || strings.ContainsRune(" \t}])", rune(line[0])) {
+		return false
+	}
+	prev = strings.TrimSpace(prev)
+	return prev == "" || strings.ContainsRune("})]", rune(prev[0]))
+}
@@ -0,0 +1,103 @@
+package mapreduce
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/file"
+)
+
+// wordCounter counts one token per whitespace separated word
+type wordCounter struct{}
+
+func (wordCounter) Count(text string) int {
+	return len(strings.Fields(text))
+}
+
+func TestBlocks(t *testing.T) {
+	text := "package main\n\nfunc a() {\n\treturn\n}\nfunc b() {\n}\n\ntype T struct{}\n"
+
+	got := blocks(text)
+	want := []string{
+		"package main\n\n",
+		"func a() {\n\treturn\n}\n",
+		"func b() {\n}\n\n",
+		"type T struct{}\n",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("blocks() = %q, want %q", got, want)
+	}
+	if strings.Join(got, "") != text {
+		t.Error("blocks() should not lose any text")
+	}
+}
+
+func TestBlocks_Diff(t *testing.T) {
+	text := "diff --git a/a.go b/a.go\n@@ -1 +1 @@\n-a\n+b\n@@ -9 +9 @@\n-c\n+d\n"
+
+	if got := blocks(text); len(got) != 3 {
+		t.Errorf("Expected the header and two hunks, got %q", got)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	var big strings.Builder
+	for i := 0; i < 10; i++ {
+		big.WriteString("func f() {\n\tone two three four five\n}\n\n")
+	}
+	attachments := []file.Attachment{
+		{Path: "small.go", Data: []byte("package small\n")},
+		{Path: "big.go", Data: []byte(big.String())},
+		{Path: "image.png", Data: []byte("png"), MimeType: "image/png"},
+	}
+
+	// Each function is 9 words, so 3 functions fit in a chunk of 27 words
+	runner := NewRunner(nil, wordCounter{}, 27+chunkOverhead, 1, logger)
+	chunks := runner.Split(attachments)
+
+	if len(chunks) != 4 {
+		t.Fatalf("Expected 4 chunks, got %d", len(chunks))
+	}
+	if chunks[0][0].Path != "small.go" || chunks[0][1].Path != "big.go" {
+		t.Errorf("Small file should share the first chunk, got %s and %s", chunks[0][0].Path, chunks[0][1].Path)
+	}
+
+	var joined strings.Builder
+	for _, chunk := range chunks {
+		words := 0
+		for _, a := range chunk {
+			if a.IsImage() {
+				t.Error("Images should be skipped")
+			}
+			if a.Path == "big.go" {
+				joined.Write(a.Data)
+				if !strings.HasPrefix(string(a.Data), "func f() {") {
+					t.Errorf("Chunk should start at a function, got %q", a.Data)
+				}
+			}
+			words += wordCounter{}.Count(string(a.Data))
+		}
+		if words > 27 {
+			t.Errorf("Chunk has %d words, over the limit of 27", words)
+		}
+	}
+	if joined.String() != big.String() {
+		t.Error("Chunks should contain the whole file")
+	}
+}
+
+func TestSplit_LongBlock(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	text := strings.Repeat("\tone two three\n", 10)
+
+	runner := NewRunner(nil, wordCounter{}, 6+chunkOverhead, 1, logger)
+	chunks := runner.Split([]file.Attachment{{Path: "long.txt", Data: []byte(text)}})
+
+	if len(chunks) != 5 {
+		t.Fatalf("Expected a block without boundaries to be split by lines into 5 chunks, got %d", len(chunks))
+	}
+}
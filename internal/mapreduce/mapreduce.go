@@ -0,0 +1,229 @@
+package mapreduce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/file"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/openai"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/tokenizer"
+)
+
+// reduceInstructions asks the model to combine the answers of the map stage
+const reduceInstructions = "The input for this request was too large for a single request, " +
+	"so it was split into parts and the request was answered for each part separately. " +
+	"Combine the partial answers below into one answer to the request, as if the whole input " +
+	"had been seen at once. Merge duplicate points and keep the requested format."
+
+// ErrNoText is returned when there is no text to split into chunks, e.g.
+// when every attachment is an image
+var ErrNoText = errors.New("no text input to split into chunks, images are skipped in map-reduce mode")
+
+// answerOverhead is the token cost of the delimiter around each partial answer
+const answerOverhead = 8
+
+// Render builds the user message for a prompt and the attachments of a chunk
+type Render func(prompt string, attachments []file.Attachment) openai.Message
+
+// Runner answers a prompt over input that is too large for one request by
+// running it on every chunk (map) and combining the answers (reduce)
+type Runner struct {
+	provider    openai.Provider
+	counter     tokenizer.Counter
+	maxTokens   int
+	concurrency int
+	logger      *slog.Logger
+}
+
+// NewRunner creates a runner whose chunks and reduce inputs hold at most
+// maxTokens tokens, sending up to concurrency requests at a time
+func NewRunner(provider openai.Provider, counter tokenizer.Counter, maxTokens, concurrency int, logger *slog.Logger) *Runner {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Runner{
+		provider:    provider,
+		counter:     counter,
+		maxTokens:   maxTokens,
+		concurrency: concurrency,
+		logger:      logger,
+	}
+}
+
+// Run answers prompt over the chunks. The messages of req are sent before
+// the user message of every request. The response schema and streaming only
+// apply to the final reduce request, and the returned usage is the total of
+// all requests.
+func (r *Runner) Run(ctx context.Context, req openai.ChatCompletionRequest, prompt string, chunks [][]file.Attachment, render Render) (*openai.ChatCompletionResponse, error) {
+	if len(chunks) == 0 {
+		return nil, ErrNoText
+	}
+	r.logger.Info("running map stage", "chunks", len(chunks), "concurrency", r.concurrency)
+	requests := make([]openai.ChatCompletionRequest, len(chunks))
+	for i, chunk := range chunks {
+		note := fmt.Sprintf("%s\n\nThe input is split into %d parts. This is part %d; answer for this part only.", prompt, len(chunks), i+1)
+		requests[i] = partial(req, render(note, chunk))
+	}
+	responses, err := r.all(ctx, requests)
+	if err != nil {
+		return nil, fmt.Errorf("map stage: %w", err)
+	}
+	usage := total(responses)
+	answers := contents(responses)
+
+	for round := 1; ; round++ {
+		groups := r.group(answers)
+		if len(groups) <= 1 {
+			break
+		}
+
+		// Too many answers for one request, reduce them in groups first
+		r.logger.Info("running intermediate reduce", "round", round, "answers", len(answers), "groups", len(groups))
+		requests = make([]openai.ChatCompletionRequest, len(groups))
+		for i, group := range groups {
+			requests[i] = partial(req, reduceMessage(prompt, group))
+		}
+		responses, err = r.all(ctx, requests)
+		if err != nil {
+			return nil, fmt.Errorf("reduce stage: %w", err)
+		}
+		usage = add(usage, total(responses))
+		answers = contents(responses)
+	}
+
+	r.logger.Info("running reduce stage", "answers", len(answers))
+	final := req
+	final.Messages = append(append([]openai.Message{}, req.Messages...), reduceMessage(prompt, answers))
+	resp, err := r.provider.CreateChatCompletion(ctx, final)
+	if err != nil {
+		return nil, fmt.Errorf("reduce stage: %w", err)
+	}
+	result := *resp
+	result.Usage = add(usage, resp.Usage)
+	return &result, nil
+}
+
+// partial returns a map or intermediate reduce request. These are plain
+// text requests that are neither streamed nor constrained by a schema.
+func partial(req openai.ChatCompletionRequest, user openai.Message) openai.ChatCompletionRequest {
+	req.Messages = append(append([]openai.Message{}, req.Messages...), user)
+	req.ResponseSchema = nil
+	req.Stream = false
+	req.OnToken = nil
+	return req
+}
+
+// reduceMessage builds the user message that combines partial answers
+func reduceMessage(prompt string, answers []string) openai.Message {
+	var b strings.Builder
+	b.WriteString(prompt)
+	b.WriteString("\n\n")
+	b.WriteString(reduceInstructions)
+	for i, answer := range answers {
+		fmt.Fprintf(&b, "\n\n<answer part=\"%d\">\n%s\n</answer>", i+1, strings.TrimSpace(answer))
+	}
+	return openai.Message{Role: "user", Content: b.String()}
+}
+
+// group packs answers into groups that fit the token limit. Every group
+// holds at least two answers so that each round reduces their number.
+func (r *Runner) group(answers []string) [][]string {
+	var groups [][]string
+	var current []string
+	used := 0
+	for _, answer := range answers {
+		tokens := r.counter.Count(answer) + answerOverhead
+		if len(current) > 1 && used+tokens > r.maxTokens {
+			groups = append(groups, current)
+			current, used = nil, 0
+		}
+		current = append(current, answer)
+		used += tokens
+	}
+	if len(current) == 1 && len(groups) > 0 {
+		groups[len(groups)-1] = append(groups[len(groups)-1], current[0])
+	} else if len(current) > 0 {
+		groups = append(groups, current)
+	}
+	return groups
+}
+
+// all sends the requests with at most r.concurrency in flight. The first
+// failure cancels the remaining requests.
+func (r *Runner) all(ctx context.Context, requests []openai.ChatCompletionRequest) ([]*openai.ChatCompletionResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	responses := make([]*openai.ChatCompletionResponse, len(requests))
+	errs := make([]error, len(requests))
+	sem := make(chan struct{}, r.concurrency)
+	var wg sync.WaitGroup
+	for i, req := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if err := ctx.Err(); err != nil {
+				errs[i] = err
+				return
+			}
+			responses[i], errs[i] = r.provider.CreateChatCompletion(ctx, req)
+			if errs[i] != nil {
+				r.logger.Error("partial request failed", "part", i+1, "error", errs[i])
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Report the failure that caused the cancellation rather than its effect
+	var first error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("part %d of %d: %w", i+1, len(requests), err)
+		if !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	if first != nil {
+		return nil, first
+	}
+	return responses, nil
+}
+
+// contents returns the text of each response
+func contents(responses []*openai.ChatCompletionResponse) []string {
+	result := make([]string, len(responses))
+	for i, resp := range responses {
+		result[i] = resp.Content
+	}
+	return result
+}
+
+// total sums the token usage of the responses
+func total(responses []*openai.ChatCompletionResponse) openai.Usage {
+	var usage openai.Usage
+	for _, resp := range responses {
+		usage = add(usage, resp.Usage)
+	}
+	return usage
+}
+
+// add returns the sum of two usages
+func add(a, b openai.Usage) openai.Usage {
+	return openai.Usage{
+		PromptTokens:     a.PromptTokens + b.PromptTokens,
+		CompletionTokens: a.CompletionTokens + b.CompletionTokens,
+		TotalTokens:      a.TotalTokens + b.TotalTokens,
+	}
+}
@@ -0,0 +1,173 @@
+package mapreduce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/file"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/openai"
+)
+
+// recordingProvider answers every request with a numbered answer and
+// records the requests and the peak number of concurrent calls
+type recordingProvider struct {
+	mu       sync.Mutex
+	requests []openai.ChatCompletionRequest
+	active   atomic.Int32
+	peak     atomic.Int32
+	fail     string // fail requests whose user message contains this text
+}
+
+func (p *recordingProvider) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (*openai.ChatCompletionResponse, error) {
+	active := p.active.Add(1)
+	defer p.active.Add(-1)
+	for {
+		peak := p.peak.Load()
+		if active <= peak || p.peak.CompareAndSwap(peak, active) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	p.mu.Lock()
+	p.requests = append(p.requests, req)
+	n := len(p.requests)
+	p.mu.Unlock()
+
+	user := req.Messages[len(req.Messages)-1].Content.(string)
+	if p.fail != "" && strings.Contains(user, p.fail) {
+		return nil, errors.New("boom")
+	}
+	return &openai.ChatCompletionResponse{
+		Content: fmt.Sprintf("answer %d", n),
+		Model:   "gpt-4o",
+		Usage:   openai.Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12},
+	}, nil
+}
+
+// render formats the chunk like a prompt followed by the file contents
+func render(prompt string, attachments []file.Attachment) openai.Message {
+	return file.BuildMessage(prompt, attachments)
+}
+
+func chunksOf(n int) [][]file.Attachment {
+	chunks := make([][]file.Attachment, n)
+	for i := range chunks {
+		chunks[i] = []file.Attachment{{Path: fmt.Sprintf("part%d.go", i+1), Data: []byte("package part")}}
+	}
+	return chunks
+}
+
+func TestRun(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	provider := &recordingProvider{}
+	runner := NewRunner(provider, wordCounter{}, 1000, 2, logger)
+
+	req := openai.ChatCompletionRequest{
+		Model:          "gpt-4o",
+		Messages:       []openai.Message{{Role: "system", Content: "be brief"}},
+		Stream:         true,
+		ResponseSchema: &openai.JSONSchema{Name: "verdict"},
+	}
+	resp, err := runner.Run(context.Background(), req, "review", chunksOf(5), render)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(provider.requests) != 6 {
+		t.Fatalf("Expected 5 map requests and 1 reduce request, got %d", len(provider.requests))
+	}
+	if peak := provider.peak.Load(); peak > 2 {
+		t.Errorf("Peak concurrency = %d, want at most 2", peak)
+	}
+	for _, r := range provider.requests[:5] {
+		if r.ResponseSchema != nil || r.Stream {
+			t.Error("Map requests should not use the schema or streaming")
+		}
+		if len(r.Messages) != 2 || r.Messages[0].Content != "be brief" {
+			t.Errorf("Map request should keep the system message, got %+v", r.Messages)
+		}
+	}
+
+	final := provider.requests[5]
+	if final.ResponseSchema == nil || !final.Stream {
+		t.Error("Reduce request should keep the schema and streaming")
+	}
+	content := final.Messages[1].Content.(string)
+	for i := 1; i <= 5; i++ {
+		if !strings.Contains(content, fmt.Sprintf("<answer part=\"%d\">", i)) {
+			t.Errorf("Reduce prompt should contain answer %d, got %q", i, content)
+		}
+	}
+
+	if resp.Content != "answer 6" {
+		t.Errorf("Content = %q, want the reduce answer", resp.Content)
+	}
+	if resp.Usage.TotalTokens != 6*12 {
+		t.Errorf("TotalTokens = %d, want %d", resp.Usage.TotalTokens, 6*12)
+	}
+}
+
+func TestRun_IntermediateReduce(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	provider := &recordingProvider{}
+	// Each answer costs 2 words plus the delimiter, so only two fit a reduce
+	runner := NewRunner(provider, wordCounter{}, 2*(2+answerOverhead), 4, logger)
+
+	if _, err := runner.Run(context.Background(), openai.ChatCompletionRequest{}, "review", chunksOf(4), render); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	// 4 map requests, 2 intermediate reduces and the final reduce
+	if len(provider.requests) != 7 {
+		t.Errorf("Expected 7 requests, got %d", len(provider.requests))
+	}
+}
+
+func TestRun_ImagesOnly(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	provider := &recordingProvider{}
+	runner := NewRunner(provider, wordCounter{}, 1000, 1, logger)
+
+	chunks := runner.Split([]file.Attachment{{Path: "logo.png", Data: []byte("png"), MimeType: "image/png"}})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := runner.Run(ctx, openai.ChatCompletionRequest{}, "review", chunks, render)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrNoText) {
+			t.Errorf("Run() error = %v, want ErrNoText", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run() did not return for input without text")
+	}
+	if len(provider.requests) != 0 {
+		t.Errorf("Expected no requests, got %d", len(provider.requests))
+	}
+}
+
+func TestRun_MapFailure(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	provider := &recordingProvider{fail: "part3.go"}
+	runner := NewRunner(provider, wordCounter{}, 1000, 1, logger)
+
+	_, err := runner.Run(context.Background(), openai.ChatCompletionRequest{}, "review", chunksOf(5), render)
+	if err == nil {
+		t.Fatal("Expected error from a failed part, got nil")
+	}
+	if !strings.Contains(err.Error(), "part 3 of 5: boom") {
+		t.Errorf("Error should name the failed part, got %v", err)
+	}
+}
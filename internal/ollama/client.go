@@ -0,0 +1,248 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/openai"
+)
+
+const defaultBaseURL = "http://localhost:11434"
+
+// Options holds the connection settings for an Ollama server
+type Options struct {
+	APIKey  string
+	BaseURL string
+	Headers map[string]string
+}
+
+// Client calls the native Ollama chat API
+type Client struct {
+	opts       Options
+	httpClient *http.Client
+	logger     *slog.Logger
+}
+
+// NewClient creates a new Ollama client
+func NewClient(opts Options, logger *slog.Logger) *Client {
+	if opts.BaseURL == "" {
+		opts.BaseURL = defaultBaseURL
+	}
+	opts.BaseURL = strings.TrimSuffix(opts.BaseURL, "/")
+	return &Client{
+		opts:       opts,
+		httpClient: http.DefaultClient,
+		logger:     logger,
+	}
+}
+
+// chatRequest is the body of an /api/chat request
+type chatRequest struct {
+	Model    string         `json:"model"`
+	Messages []message      `json:"messages"`
+	Stream   bool           `json:"stream"`
+	Format   map[string]any `json:"format,omitempty"`
+	Options  map[string]any `json:"options,omitempty"`
+}
+
+// message is a single conversation turn; images hold base64 encoded data
+type message struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"`
+}
+
+// chatResponse is a complete response, or one chunk of a streamed response
+type chatResponse struct {
+	Model           string  `json:"model"`
+	Message         message `json:"message"`
+	Done            bool    `json:"done"`
+	DoneReason      string  `json:"done_reason"`
+	PromptEvalCount int64   `json:"prompt_eval_count"`
+	EvalCount       int64   `json:"eval_count"`
+	Error           string  `json:"error"`
+}
+
+// CreateChatCompletion sends a request to /api/chat and returns the response
+func (c *Client) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (*openai.ChatCompletionResponse, error) {
+	c.logger.Info("creating ollama chat",
+		"model", req.Model,
+		"temperature", req.Temperature,
+		"max_tokens", req.MaxTokens,
+		"num_messages", len(req.Messages),
+		"stream", req.Stream,
+	)
+
+	payload, err := json.Marshal(c.buildRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("error encoding request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.opts.BaseURL+"/api/chat", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.opts.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.opts.APIKey)
+	}
+	for key, value := range c.opts.Headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		c.logger.Error("Ollama API call failed", "error", err)
+		return nil, fmt.Errorf("Ollama API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		var apiErr chatResponse
+		message := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			message = apiErr.Error
+		}
+		c.logger.Error("Ollama API call failed", "status", resp.StatusCode, "error", message)
+		return nil, &openai.APIError{
+			Provider:   "Ollama",
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+			Message:    message,
+		}
+	}
+
+	var result *chatResponse
+	if req.Stream {
+		result, err = readStream(resp.Body, req.OnToken)
+	} else {
+		result = &chatResponse{}
+		err = json.NewDecoder(resp.Body).Decode(result)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	if result.Message.Content == "" {
+		c.logger.Warn("empty content in response")
+		return nil, fmt.Errorf("empty response from Ollama")
+	}
+
+	usage := openai.Usage{
+		PromptTokens:     result.PromptEvalCount,
+		CompletionTokens: result.EvalCount,
+		TotalTokens:      result.PromptEvalCount + result.EvalCount,
+	}
+	c.logger.Info("ollama chat successful",
+		"prompt_tokens", usage.PromptTokens,
+		"completion_tokens", usage.CompletionTokens,
+		"total_tokens", usage.TotalTokens,
+		"done_reason", result.DoneReason,
+	)
+
+	return &openai.ChatCompletionResponse{
+		Content:      result.Message.Content,
+		Model:        result.Model,
+		FinishReason: result.DoneReason,
+		Usage:        usage,
+	}, nil
+}
+
+// readStream accumulates a newline delimited stream of chunks into a single
+// response, passing each chunk of content to onToken. The final chunk
+// carries the token counts.
+func readStream(r io.Reader, onToken func(string)) (*chatResponse, error) {
+	var result chatResponse
+	var content strings.Builder
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var chunk chatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return nil, err
+		}
+		if chunk.Error != "" {
+			return nil, fmt.Errorf("stream error: %s", chunk.Error)
+		}
+		content.WriteString(chunk.Message.Content)
+		if onToken != nil && chunk.Message.Content != "" {
+			onToken(chunk.Message.Content)
+		}
+		if chunk.Done {
+			result = chunk
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !result.Done {
+		return nil, fmt.Errorf("stream ended before completion")
+	}
+
+	result.Message.Content = content.String()
+	return &result, nil
+}
+
+// buildRequest converts a chat completion request into an /api/chat request
+func (c *Client) buildRequest(req openai.ChatCompletionRequest) chatRequest {
+	body := chatRequest{
+		Model:    req.Model,
+		Stream:   req.Stream,
+		Messages: make([]message, 0, len(req.Messages)),
+		Options:  map[string]any{},
+	}
+	if req.Temperature > 0 {
+		body.Options["temperature"] = req.Temperature
+	}
+	if req.MaxTokens > 0 {
+		body.Options["num_predict"] = req.MaxTokens
+	}
+	if req.ResponseSchema != nil {
+		body.Format = req.ResponseSchema.Schema
+	}
+
+	for _, msg := range req.Messages {
+		body.Messages = append(body.Messages, c.convertMessage(msg))
+	}
+	return body
+}
+
+// convertMessage converts our internal Message type to Ollama format. Text
+// parts are joined and data URL images move to the images field.
+func (c *Client) convertMessage(msg openai.Message) message {
+	parts, ok := msg.Content.([]openai.MessagePart)
+	if !ok {
+		text, _ := msg.Content.(string)
+		return message{Role: msg.Role, Content: text}
+	}
+
+	result := message{Role: msg.Role}
+	var texts []string
+	for _, part := range parts {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		} else if part.Type == "image_url" && part.ImageURL != nil {
+			_, data, found := strings.Cut(part.ImageURL.URL, ";base64,")
+			if !found {
+				c.logger.Warn("skipping image that is not a base64 data URL")
+				continue
+			}
+			result.Images = append(result.Images, data)
+		}
+	}
+	result.Content = strings.Join(texts, "\n\n")
+	return result
+}
@@ -0,0 +1,155 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/openai"
+)
+
+func newTestServer(t *testing.T, received *chatRequest, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("Path = %q, want /api/chat", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(received); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		w.Write([]byte(body))
+	}))
+}
+
+func TestCreateChatCompletion(t *testing.T) {
+	var received chatRequest
+	server := newTestServer(t, &received, `{
+		"model": "llama3.2-vision",
+		"message": {"role": "assistant", "content": "A diagram"},
+		"done": true,
+		"done_reason": "stop",
+		"prompt_eval_count": 20,
+		"eval_count": 3
+	}`)
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	client := NewClient(Options{BaseURL: server.URL}, logger)
+
+	resp, err := client.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model: "llama3.2-vision",
+		Messages: []openai.Message{
+			{Role: "system", Content: "You are helpful."},
+			{Role: "user", Content: []openai.MessagePart{
+				{Type: "text", Text: "Describe this image"},
+				{Type: "image_url", ImageURL: &openai.ImageURL{URL: "data:image/png;base64,aGVsbG8="}},
+			}},
+		},
+		Temperature: 0.5,
+		MaxTokens:   100,
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion() error = %v", err)
+	}
+
+	if received.Stream {
+		t.Error("Stream should be disabled by default")
+	}
+	if len(received.Messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(received.Messages))
+	}
+	user := received.Messages[1]
+	if user.Content != "Describe this image" {
+		t.Errorf("User content = %q, want prompt", user.Content)
+	}
+	if len(user.Images) != 1 || user.Images[0] != "aGVsbG8=" {
+		t.Errorf("Images = %v, want base64 image data", user.Images)
+	}
+	if received.Options["num_predict"] != float64(100) {
+		t.Errorf("num_predict = %v, want 100", received.Options["num_predict"])
+	}
+
+	if resp.Content != "A diagram" {
+		t.Errorf("Content = %q, want %q", resp.Content, "A diagram")
+	}
+	if resp.Usage.TotalTokens != 23 {
+		t.Errorf("TotalTokens = %d, want 23", resp.Usage.TotalTokens)
+	}
+}
+
+func TestCreateChatCompletion_Stream(t *testing.T) {
+	var received chatRequest
+	server := newTestServer(t, &received, `{"model":"llama3.2","message":{"role":"assistant","content":"Hel"},"done":false}
+{"model":"llama3.2","message":{"role":"assistant","content":"lo"},"done":false}
+{"model":"llama3.2","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":5,"eval_count":2}
+`)
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	client := NewClient(Options{BaseURL: server.URL}, logger)
+
+	var tokens []string
+	resp, err := client.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model:    "llama3.2",
+		Messages: []openai.Message{{Role: "user", Content: "Say hello"}},
+		Stream:   true,
+		OnToken: func(text string) {
+			tokens = append(tokens, text)
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion() error = %v", err)
+	}
+
+	if !received.Stream {
+		t.Error("Stream should be enabled in the request")
+	}
+	if resp.Content != "Hello" {
+		t.Errorf("Content = %q, want %q", resp.Content, "Hello")
+	}
+	if len(tokens) != 2 {
+		t.Errorf("tokens = %v, want 2 chunks", tokens)
+	}
+	if resp.Usage.PromptTokens != 5 || resp.Usage.CompletionTokens != 2 {
+		t.Errorf("Usage = %+v, want 5/2", resp.Usage)
+	}
+	if resp.FinishReason != "stop" {
+		t.Errorf("FinishReason = %q, want stop", resp.FinishReason)
+	}
+}
+
+func TestCreateChatCompletion_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "model \"missing\" not found"}`))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	client := NewClient(Options{BaseURL: server.URL}, logger)
+
+	_, err := client.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model:    "missing",
+		Messages: []openai.Message{{Role: "user", Content: "Hello"}},
+	})
+	if err == nil {
+		t.Fatal("Expected error for missing model, got nil")
+	}
+}
+
+func TestBuildRequest_ResponseSchema(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	client := NewClient(Options{}, logger)
+
+	body := client.buildRequest(openai.ChatCompletionRequest{
+		Model:          "llama3.2",
+		Messages:       []openai.Message{{Role: "user", Content: "Review"}},
+		ResponseSchema: &openai.JSONSchema{Name: "verdict", Schema: map[string]any{"type": "object"}},
+	})
+	if body.Format["type"] != "object" {
+		t.Errorf("Format = %v, want schema", body.Format)
+	}
+}
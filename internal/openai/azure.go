@@ -0,0 +1,32 @@
+package openai
+
+import (
+	"log/slog"
+
+	"github.com/openai/openai-go/v3/azure"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// AzureConfig holds the connection settings for an Azure OpenAI resource
+type AzureConfig struct {
+	Endpoint   string
+	APIVersion string
+	APIKey     string
+	ADToken    string
+	Headers    map[string]string
+}
+
+// NewAzureClient creates a client for an Azure OpenAI resource. Requests are
+// routed to the deployment named by the request model. The client
+// authenticates with the API key when set, otherwise with the Azure AD token.
+func NewAzureClient(cfg AzureConfig, logger *slog.Logger) *Client {
+	opts := []option.RequestOption{azure.WithEndpoint(cfg.Endpoint, cfg.APIVersion)}
+	if cfg.APIKey != "" {
+		opts = append(opts, azure.WithAPIKey(cfg.APIKey))
+	} else {
+		opts = append(opts, option.WithHeader("Authorization", "Bearer "+cfg.ADToken))
+	}
+	opts = append(opts, headerOptions(cfg.Headers)...)
+
+	return newClient(logger, opts...)
+}
@@ -0,0 +1,92 @@
+package openai
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+const chatCompletionJSON = `{
+	"id": "chatcmpl-123",
+	"object": "chat.completion",
+	"created": 1700000000,
+	"model": "gpt-4o-mini",
+	"choices": [{
+		"index": 0,
+		"message": {"role": "assistant", "content": "Hello from Azure"},
+		"finish_reason": "stop"
+	}],
+	"usage": {"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}
+}`
+
+func TestAzureClient_CreateChatCompletion(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        AzureConfig
+		wantHeader string
+		wantValue  string
+	}{
+		{
+			name:       "api key",
+			cfg:        AzureConfig{APIKey: "azure-key"},
+			wantHeader: "Api-Key",
+			wantValue:  "azure-key",
+		},
+		{
+			name:       "azure ad token",
+			cfg:        AzureConfig{ADToken: "aad-token"},
+			wantHeader: "Authorization",
+			wantValue:  "Bearer aad-token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/openai/deployments/my-deployment/chat/completions" {
+					t.Errorf("Path = %q, want deployment route", r.URL.Path)
+				}
+				if got := r.URL.Query().Get("api-version"); got != "2024-10-21" {
+					t.Errorf("api-version = %q, want 2024-10-21", got)
+				}
+				if got := r.Header.Get(tt.wantHeader); got != tt.wantValue {
+					t.Errorf("%s header = %q, want %q", tt.wantHeader, got, tt.wantValue)
+				}
+				io.Copy(io.Discard, r.Body)
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(chatCompletionJSON))
+			}))
+			defer server.Close()
+
+			cfg := tt.cfg
+			cfg.Endpoint = server.URL
+			cfg.APIVersion = "2024-10-21"
+
+			logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+			client := NewAzureClient(cfg, logger)
+
+			resp, err := client.CreateChatCompletion(context.Background(), ChatCompletionRequest{
+				Model: "my-deployment",
+				Messages: []Message{
+					{Role: "user", Content: "Hello"},
+				},
+			})
+			if err != nil {
+				t.Fatalf("CreateChatCompletion() error = %v", err)
+			}
+			if resp.Content != "Hello from Azure" {
+				t.Errorf("Content = %q, want %q", resp.Content, "Hello from Azure")
+			}
+			if resp.Usage.TotalTokens != 15 {
+				t.Errorf("TotalTokens = %d, want 15", resp.Usage.TotalTokens)
+			}
+			if resp.FinishReason != "stop" {
+				t.Errorf("FinishReason = %q, want stop", resp.FinishReason)
+			}
+		})
+	}
+}
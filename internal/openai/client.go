@@ -2,8 +2,10 @@ package openai
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
@@ -15,9 +17,45 @@ type Client struct {
 	logger *slog.Logger
 }
 
+// Options holds the connection settings for an OpenAI-compatible server
+type Options struct {
+	APIKey       string
+	BaseURL      string
+	Organization string
+	Project      string
+	Headers      map[string]string
+}
+
 // NewClient creates a new OpenAI client using the official SDK
-func NewClient(apiKey string, logger *slog.Logger) *Client {
-	oaiClient := openai.NewClient(option.WithAPIKey(apiKey))
+func NewClient(opts Options, logger *slog.Logger) *Client {
+	reqOpts := []option.RequestOption{option.WithAPIKey(opts.APIKey)}
+	if opts.BaseURL != "" {
+		reqOpts = append(reqOpts, option.WithBaseURL(opts.BaseURL))
+	}
+	if opts.Organization != "" {
+		reqOpts = append(reqOpts, option.WithOrganization(opts.Organization))
+	}
+	if opts.Project != "" {
+		reqOpts = append(reqOpts, option.WithProject(opts.Project))
+	}
+	reqOpts = append(reqOpts, headerOptions(opts.Headers)...)
+	return newClient(logger, reqOpts...)
+}
+
+// headerOptions converts extra headers into SDK request options
+func headerOptions(headers map[string]string) []option.RequestOption {
+	opts := make([]option.RequestOption, 0, len(headers))
+	for key, value := range headers {
+		opts = append(opts, option.WithHeader(key, value))
+	}
+	return opts
+}
+
+// newClient creates a client with the given SDK request options. The SDK's
+// own retries are disabled because retries are handled by RetryProvider.
+func newClient(logger *slog.Logger, opts ...option.RequestOption) *Client {
+	opts = append([]option.RequestOption{option.WithMaxRetries(0)}, opts...)
+	oaiClient := openai.NewClient(opts...)
 	return &Client{
 		client: &oaiClient,
 		logger: logger,
@@ -28,6 +66,26 @@ func NewClient(apiKey string, logger *slog.Logger) *Client {
 type Message struct {
 	Role    string
 	Content interface{} // Can be string or []MessagePart for multimodal
+	// ToolCalls are the tools called by an assistant message
+	ToolCalls []ToolCall
+	// ToolCallID identifies the call answered by a "tool" message
+	ToolCallID string
+}
+
+// Tool is a function the model may call. Parameters is the JSON Schema of
+// the function arguments.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]any
+}
+
+// ToolCall is a call of a tool requested by the model. Arguments is a JSON
+// object encoded as a string.
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
 }
 
 // MessagePart represents a part of a multimodal message
@@ -48,6 +106,18 @@ type ChatCompletionRequest struct {
 	Messages    []Message
 	Temperature float64
 	MaxTokens   int64
+	Stream      bool              // request an incremental response where the provider supports it
+	OnToken     func(text string) // called with each streamed chunk of content
+	// ResponseSchema constrains the response to JSON matching the schema
+	ResponseSchema *JSONSchema
+	// Tools are the functions the model may call instead of answering
+	Tools []Tool
+}
+
+// JSONSchema is a named JSON Schema document for structured outputs
+type JSONSchema struct {
+	Name   string
+	Schema map[string]any
 }
 
 // Usage represents token usage information
@@ -59,8 +129,12 @@ type Usage struct {
 
 // ChatCompletionResponse represents the response from OpenAI
 type ChatCompletionResponse struct {
-	Content string
-	Usage   Usage
+	Content      string
+	Model        string
+	FinishReason string
+	Usage        Usage
+	// ToolCalls are set when the model calls tools instead of answering
+	ToolCalls []ToolCall
 }
 
 // CreateChatCompletion sends a request to OpenAI and returns the response
@@ -70,6 +144,8 @@ func (c *Client) CreateChatCompletion(ctx context.Context, req ChatCompletionReq
 		"temperature", req.Temperature,
 		"max_tokens", req.MaxTokens,
 		"num_messages", len(req.Messages),
+		"stream", req.Stream,
+		"num_tools", len(req.Tools),
 	)
 
 	// Convert our messages to OpenAI SDK format
@@ -90,12 +166,36 @@ func (c *Client) CreateChatCompletion(ctx context.Context, req ChatCompletionReq
 	if req.MaxTokens > 0 {
 		params.MaxTokens = openai.Int(req.MaxTokens)
 	}
+	for _, tool := range req.Tools {
+		params.Tools = append(params.Tools, openai.ChatCompletionFunctionTool(openai.FunctionDefinitionParam{
+			Name:        tool.Name,
+			Description: openai.String(tool.Description),
+			Parameters:  tool.Parameters,
+		}))
+	}
+	if req.ResponseSchema != nil {
+		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
+				JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:   req.ResponseSchema.Name,
+					Schema: req.ResponseSchema.Schema,
+					Strict: openai.Bool(true),
+				},
+			},
+		}
+	}
 
 	// Make the API call
-	resp, err := c.client.Chat.Completions.New(ctx, params)
+	var resp *openai.ChatCompletion
+	var err error
+	if req.Stream {
+		resp, err = c.stream(ctx, params, req.OnToken)
+	} else {
+		resp, err = c.client.Chat.Completions.New(ctx, params)
+	}
 	if err != nil {
 		c.logger.Error("OpenAI API call failed", "error", err)
-		return nil, fmt.Errorf("OpenAI API error: %w", err)
+		return nil, wrapError(err)
 	}
 
 	// Extract the response content
@@ -104,8 +204,17 @@ func (c *Client) CreateChatCompletion(ctx context.Context, req ChatCompletionReq
 		return nil, fmt.Errorf("no response from OpenAI")
 	}
 
-	content := resp.Choices[0].Message.Content
-	if content == "" {
+	choice := resp.Choices[0]
+	content := choice.Message.Content
+	var toolCalls []ToolCall
+	for _, call := range choice.Message.ToolCalls {
+		toolCalls = append(toolCalls, ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+	if content == "" && len(toolCalls) == 0 {
 		c.logger.Warn("empty content in response")
 		return nil, fmt.Errorf("empty response from OpenAI")
 	}
@@ -114,18 +223,71 @@ func (c *Client) CreateChatCompletion(ctx context.Context, req ChatCompletionReq
 		"prompt_tokens", resp.Usage.PromptTokens,
 		"completion_tokens", resp.Usage.CompletionTokens,
 		"total_tokens", resp.Usage.TotalTokens,
+		"finish_reason", choice.FinishReason,
+		"tool_calls", len(toolCalls),
 	)
 
 	return &ChatCompletionResponse{
-		Content: content,
+		Content:      content,
+		Model:        resp.Model,
+		FinishReason: choice.FinishReason,
 		Usage: Usage{
 			PromptTokens:     resp.Usage.PromptTokens,
 			CompletionTokens: resp.Usage.CompletionTokens,
 			TotalTokens:      resp.Usage.TotalTokens,
 		},
+		ToolCalls: toolCalls,
 	}, nil
 }
 
+// stream sends the request with streaming enabled, passing each content
+// delta to onToken and accumulating the chunks into a full completion.
+// Usage is requested through stream_options since it is not sent otherwise.
+func (c *Client) stream(ctx context.Context, params openai.ChatCompletionNewParams, onToken func(string)) (*openai.ChatCompletion, error) {
+	params.StreamOptions = openai.ChatCompletionStreamOptionsParam{
+		IncludeUsage: openai.Bool(true),
+	}
+
+	stream := c.client.Chat.Completions.NewStreaming(ctx, params)
+	defer stream.Close()
+
+	acc := openai.ChatCompletionAccumulator{}
+	for stream.Next() {
+		chunk := stream.Current()
+		acc.AddChunk(chunk)
+		if onToken != nil && len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			onToken(chunk.Choices[0].Delta.Content)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+	return &acc.ChatCompletion, nil
+}
+
+// wrapError converts SDK errors into an APIError so callers can inspect the
+// status code and rate limit headers
+func wrapError(err error) error {
+	var sdkErr *openai.Error
+	if !errors.As(err, &sdkErr) {
+		return fmt.Errorf("OpenAI API error: %w", err)
+	}
+
+	apiErr := &APIError{
+		Provider:   "OpenAI",
+		StatusCode: sdkErr.StatusCode,
+		Message:    sdkErr.Message,
+		Err:        err,
+	}
+	if sdkErr.Response != nil {
+		apiErr.Header = sdkErr.Response.Header
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(sdkErr.StatusCode)
+	}
+	return apiErr
+}
+
 // convertMessage converts our internal Message type to OpenAI SDK format
 func convertMessage(msg Message) openai.ChatCompletionMessageParamUnion {
 	switch msg.Role {
@@ -150,7 +312,25 @@ func convertMessage(msg Message) openai.ChatCompletionMessageParamUnion {
 		// Simple text message
 		return openai.UserMessage(msg.Content.(string))
 	case "assistant":
-		return openai.AssistantMessage(msg.Content.(string))
+		// Content is optional when the assistant calls tools
+		var assistant openai.ChatCompletionAssistantMessageParam
+		if content, _ := msg.Content.(string); content != "" {
+			assistant.Content.OfString = openai.String(content)
+		}
+		for _, call := range msg.ToolCalls {
+			assistant.ToolCalls = append(assistant.ToolCalls, openai.ChatCompletionMessageToolCallUnionParam{
+				OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
+					ID: call.ID,
+					Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{
+						Name:      call.Name,
+						Arguments: call.Arguments,
+					},
+				},
+			})
+		}
+		return openai.ChatCompletionMessageParamUnion{OfAssistant: &assistant}
+	case "tool":
+		return openai.ToolMessage(msg.Content.(string), msg.ToolCallID)
 	default:
 		return openai.UserMessage(msg.Content.(string))
 	}
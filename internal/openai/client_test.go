@@ -0,0 +1,246 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestClient_CustomBaseURLAndHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Path = %q, want /v1/chat/completions", r.URL.Path)
+		}
+		expected := map[string]string{
+			"Authorization":       "Bearer test-key",
+			"OpenAI-Organization": "org-123",
+			"OpenAI-Project":      "proj-456",
+			"X-Gateway-Team":      "platform",
+		}
+		for key, want := range expected {
+			if got := r.Header.Get(key); got != want {
+				t.Errorf("%s header = %q, want %q", key, got, want)
+			}
+		}
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(chatCompletionJSON))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	client := NewClient(Options{
+		APIKey:       "test-key",
+		BaseURL:      server.URL + "/v1/",
+		Organization: "org-123",
+		Project:      "proj-456",
+		Headers:      map[string]string{"X-Gateway-Team": "platform"},
+	}, logger)
+
+	resp, err := client.CreateChatCompletion(context.Background(), ChatCompletionRequest{
+		Model: "gpt-4o-mini",
+		Messages: []Message{
+			{Role: "user", Content: "Hello"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion() error = %v", err)
+	}
+	if resp.Model != "gpt-4o-mini" {
+		t.Errorf("Model = %q, want gpt-4o-mini", resp.Model)
+	}
+}
+
+func TestClient_Stream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if body["stream"] != true {
+			t.Errorf("stream = %v, want true", body["stream"])
+		}
+		options, _ := body["stream_options"].(map[string]interface{})
+		if options["include_usage"] != true {
+			t.Errorf("stream_options = %v, want include_usage", body["stream_options"])
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunks := []string{
+			`{"id":"c1","object":"chat.completion.chunk","created":1,"model":"gpt-4o-mini","choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"}}]}`,
+			`{"id":"c1","object":"chat.completion.chunk","created":1,"model":"gpt-4o-mini","choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":"stop"}]}`,
+			`{"id":"c1","object":"chat.completion.chunk","created":1,"model":"gpt-4o-mini","choices":[],"usage":{"prompt_tokens":7,"completion_tokens":2,"total_tokens":9}}`,
+		}
+		for _, chunk := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	client := NewClient(Options{APIKey: "test-key", BaseURL: server.URL}, logger)
+
+	var tokens []string
+	resp, err := client.CreateChatCompletion(context.Background(), ChatCompletionRequest{
+		Model:    "gpt-4o-mini",
+		Messages: []Message{{Role: "user", Content: "Say hello"}},
+		Stream:   true,
+		OnToken: func(text string) {
+			tokens = append(tokens, text)
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion() error = %v", err)
+	}
+
+	if len(tokens) != 2 || tokens[0] != "Hel" || tokens[1] != "lo" {
+		t.Errorf("tokens = %v, want [Hel lo]", tokens)
+	}
+	if resp.Content != "Hello" {
+		t.Errorf("Content = %q, want Hello", resp.Content)
+	}
+	if resp.FinishReason != "stop" {
+		t.Errorf("FinishReason = %q, want stop", resp.FinishReason)
+	}
+	if resp.Usage.TotalTokens != 9 {
+		t.Errorf("TotalTokens = %d, want 9", resp.Usage.TotalTokens)
+	}
+}
+
+func TestClient_StreamInterrupted(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"gpt-4o-mini","choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"}}]}`+"\n\n")
+		// The connection drops in the middle of the next chunk
+		fmt.Fprint(w, `data: {"id":"c1","object":"chat.compl`+"\n\n")
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	var provider Provider = NewClient(Options{APIKey: "test-key", BaseURL: server.URL}, logger)
+	provider = NewRetryProvider(provider, RetryPolicy{MaxRetries: 2}, logger)
+	provider = NewFallbackProvider(provider, []string{"gpt-4o"}, logger)
+
+	var tokens []string
+	_, err := provider.CreateChatCompletion(context.Background(), ChatCompletionRequest{
+		Model:    "gpt-4o-mini",
+		Messages: []Message{{Role: "user", Content: "Say hello"}},
+		Stream:   true,
+		OnToken: func(text string) {
+			tokens = append(tokens, text)
+		},
+	})
+	if !errors.Is(err, ErrStreamInterrupted) {
+		t.Fatalf("Expected ErrStreamInterrupted, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Interrupted stream should not be retried or fall back, got %d requests", requests)
+	}
+	if len(tokens) != 1 || tokens[0] != "Hel" {
+		t.Errorf("tokens = %v, want [Hel]", tokens)
+	}
+}
+
+func TestClient_ResponseSchema(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		format, _ := body["response_format"].(map[string]interface{})
+		if format["type"] != "json_schema" {
+			t.Fatalf("response_format = %v, want json_schema", body["response_format"])
+		}
+		jsonSchema, _ := format["json_schema"].(map[string]interface{})
+		if jsonSchema["name"] != "verdict" || jsonSchema["strict"] != true {
+			t.Errorf("json_schema = %v, want strict schema named verdict", jsonSchema)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(chatCompletionJSON))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	client := NewClient(Options{APIKey: "test-key", BaseURL: server.URL}, logger)
+
+	_, err := client.CreateChatCompletion(context.Background(), ChatCompletionRequest{
+		Model:    "gpt-4o-mini",
+		Messages: []Message{{Role: "user", Content: "Review"}},
+		ResponseSchema: &JSONSchema{
+			Name:   "verdict",
+			Schema: map[string]any{"type": "object"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion() error = %v", err)
+	}
+}
+
+func TestClient_Tools(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Tools []struct {
+				Type     string `json:"type"`
+				Function struct {
+					Name string `json:"name"`
+				} `json:"function"`
+			} `json:"tools"`
+			Messages []map[string]interface{} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if len(body.Tools) != 1 || body.Tools[0].Type != "function" || body.Tools[0].Function.Name != "read_file" {
+			t.Errorf("tools = %+v, want the read_file function", body.Tools)
+		}
+		if len(body.Messages) != 3 {
+			t.Fatalf("Expected 3 messages, got %d", len(body.Messages))
+		}
+		if calls, _ := body.Messages[1]["tool_calls"].([]interface{}); len(calls) != 1 {
+			t.Errorf("Assistant message should carry the tool call, got %v", body.Messages[1])
+		}
+		if body.Messages[2]["role"] != "tool" || body.Messages[2]["tool_call_id"] != "call_1" {
+			t.Errorf("Tool message = %v", body.Messages[2])
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "chatcmpl-2",
+			"object": "chat.completion",
+			"model": "gpt-4o-mini",
+			"choices": [{"index": 0, "finish_reason": "tool_calls", "message": {"role": "assistant", "content": null,
+				"tool_calls": [{"id": "call_2", "type": "function", "function": {"name": "list_dir", "arguments": "{\"path\":\".\"}"}}]}}],
+			"usage": {"prompt_tokens": 5, "completion_tokens": 2, "total_tokens": 7}
+		}`))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	client := NewClient(Options{APIKey: "test-key", BaseURL: server.URL}, logger)
+
+	resp, err := client.CreateChatCompletion(context.Background(), ChatCompletionRequest{
+		Model: "gpt-4o-mini",
+		Messages: []Message{
+			{Role: "user", Content: "Review"},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Name: "read_file", Arguments: `{"path":"main.go"}`}}},
+			{Role: "tool", ToolCallID: "call_1", Content: "package main"},
+		},
+		Tools: []Tool{{Name: "read_file", Description: "Read a file", Parameters: map[string]any{"type": "object"}}},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion() error = %v", err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "list_dir" || resp.ToolCalls[0].Arguments != `{"path":"."}` {
+		t.Errorf("ToolCalls = %+v", resp.ToolCalls)
+	}
+}
@@ -0,0 +1,51 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrStreamInterrupted is returned when a streamed request fails after part
+// of the response was printed. It is never retried, since a second attempt
+// would print its answer after the partial first one.
+var ErrStreamInterrupted = errors.New("stream interrupted after the response started")
+
+// APIError is returned by providers when the API answers with an error status
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Header     http.Header
+	Message    string
+	Err        error
+}
+
+// Error formats the error with the provider name and status code
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API error (status %d): %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// Unwrap returns the underlying SDK error, if any
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable reports whether a failed request may succeed when retried.
+// Rate limits, server errors and transport failures are retryable; other
+// API errors, context cancellation and interrupted streams are not.
+func IsRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrStreamInterrupted) {
+		return false
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return true
+	}
+	switch apiErr.StatusCode {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests:
+		return true
+	}
+	return apiErr.StatusCode >= 500
+}
@@ -0,0 +1,65 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// FallbackProvider re-issues a failed request against the next model in an
+// ordered list. The response model records which model actually answered.
+type FallbackProvider struct {
+	provider Provider
+	models   []string
+	logger   *slog.Logger
+}
+
+// NewFallbackProvider wraps provider so that the given models are tried in
+// order after the request model fails
+func NewFallbackProvider(provider Provider, models []string, logger *slog.Logger) *FallbackProvider {
+	return &FallbackProvider{
+		provider: provider,
+		models:   models,
+		logger:   logger,
+	}
+}
+
+// CreateChatCompletion tries the request model, then each fallback model,
+// until one succeeds. Cancellation, timeouts and streams that already
+// printed part of a response stop the chain immediately.
+func (f *FallbackProvider) CreateChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	models := append([]string{req.Model}, f.models...)
+	streamed := trackTokens(&req)
+
+	var errs []error
+	for i, model := range models {
+		req.Model = model
+		resp, err := f.provider.CreateChatCompletion(ctx, req)
+		if err == nil {
+			if resp.Model == "" {
+				resp.Model = model
+			}
+			if i > 0 {
+				f.logger.Info("fallback model answered", "model", model, "requested_model", models[0])
+			}
+			return resp, nil
+		}
+
+		if streamed() && !errors.Is(err, ErrStreamInterrupted) {
+			err = fmt.Errorf("%w: %w", ErrStreamInterrupted, err)
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", model, err))
+		if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrStreamInterrupted) {
+			break
+		}
+		if i+1 < len(models) {
+			f.logger.Warn("model failed, trying fallback",
+				"model", model,
+				"fallback_model", models[i+1],
+				"error", err,
+			)
+		}
+	}
+	return nil, errors.Join(errs...)
+}
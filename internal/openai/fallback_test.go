@@ -0,0 +1,89 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+)
+
+// modelProvider fails for the listed models and records the models it was asked for
+type modelProvider struct {
+	failing map[string]error
+	models  []string
+}
+
+func (m *modelProvider) CreateChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	m.models = append(m.models, req.Model)
+	if err, ok := m.failing[req.Model]; ok {
+		return nil, err
+	}
+	return &ChatCompletionResponse{Content: "answer from " + req.Model}, nil
+}
+
+func TestFallbackProvider(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	contextLength := &APIError{Provider: "Test", StatusCode: 400, Message: "context_length_exceeded"}
+
+	tests := []struct {
+		name       string
+		failing    map[string]error
+		wantModel  string
+		wantErr    bool
+		wantModels []string
+	}{
+		{
+			name:       "primary succeeds",
+			failing:    map[string]error{},
+			wantModel:  "gpt-4o-mini",
+			wantModels: []string{"gpt-4o-mini"},
+		},
+		{
+			name:       "falls back after context length error",
+			failing:    map[string]error{"gpt-4o-mini": contextLength},
+			wantModel:  "gpt-4o",
+			wantModels: []string{"gpt-4o-mini", "gpt-4o"},
+		},
+		{
+			name: "all models fail",
+			failing: map[string]error{
+				"gpt-4o-mini":  contextLength,
+				"gpt-4o":       errors.New("giving up after 3 attempts"),
+				"gpt-4.1-mini": contextLength,
+			},
+			wantErr:    true,
+			wantModels: []string{"gpt-4o-mini", "gpt-4o", "gpt-4.1-mini"},
+		},
+		{
+			name:       "timeout stops the chain",
+			failing:    map[string]error{"gpt-4o-mini": context.DeadlineExceeded},
+			wantErr:    true,
+			wantModels: []string{"gpt-4o-mini"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &modelProvider{failing: tt.failing}
+			provider := NewFallbackProvider(inner, []string{"gpt-4o", "gpt-4.1-mini"}, logger)
+
+			resp, err := provider.CreateChatCompletion(context.Background(), ChatCompletionRequest{Model: "gpt-4o-mini"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CreateChatCompletion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && resp.Model != tt.wantModel {
+				t.Errorf("Model = %q, want %q", resp.Model, tt.wantModel)
+			}
+			if len(inner.models) != len(tt.wantModels) {
+				t.Fatalf("models tried = %v, want %v", inner.models, tt.wantModels)
+			}
+			for i := range tt.wantModels {
+				if inner.models[i] != tt.wantModels[i] {
+					t.Errorf("models tried = %v, want %v", inner.models, tt.wantModels)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package openai
+
+import "context"
+
+// Provider is an LLM backend that can answer chat completion requests
+type Provider interface {
+	CreateChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error)
+}
@@ -0,0 +1,177 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// RetryProvider retries retryable errors from another provider with
+// exponential backoff and jitter. Delays requested by the server through
+// Retry-After or rate limit reset headers take precedence. The request
+// context bounds the whole loop.
+type RetryProvider struct {
+	provider Provider
+	policy   RetryPolicy
+	logger   *slog.Logger
+}
+
+// NewRetryProvider wraps provider with the given retry policy
+func NewRetryProvider(provider Provider, policy RetryPolicy, logger *slog.Logger) *RetryProvider {
+	return &RetryProvider{
+		provider: provider,
+		policy:   policy,
+		logger:   logger,
+	}
+}
+
+// CreateChatCompletion calls the wrapped provider until it succeeds, fails
+// with a non-retryable error or the retry budget is exhausted
+func (r *RetryProvider) CreateChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	streamed := trackTokens(&req)
+	for attempt := 1; ; attempt++ {
+		resp, err := r.provider.CreateChatCompletion(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if streamed() && !errors.Is(err, ErrStreamInterrupted) {
+			err = fmt.Errorf("%w: %w", ErrStreamInterrupted, err)
+		}
+
+		if !IsRetryable(err) {
+			return nil, err
+		}
+		if attempt > r.policy.MaxRetries {
+			if r.policy.MaxRetries > 0 {
+				return nil, fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+			}
+			return nil, err
+		}
+
+		delay := r.delay(attempt, err)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return nil, fmt.Errorf("retry delay %s exceeds remaining timeout: %w", delay, err)
+		}
+
+		r.logger.Warn("request failed, retrying",
+			"attempt", attempt,
+			"max_retries", r.policy.MaxRetries,
+			"delay", delay.String(),
+			"error", err,
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w: %w", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// trackTokens wraps the streaming callback of req and returns a function
+// reporting whether any token has been delivered
+func trackTokens(req *ChatCompletionRequest) func() bool {
+	onToken := req.OnToken
+	if onToken == nil {
+		return func() bool { return false }
+	}
+	var started bool
+	req.OnToken = func(text string) {
+		started = true
+		onToken(text)
+	}
+	return func() bool { return started }
+}
+
+// delay returns how long to wait before the next attempt
+func (r *RetryProvider) delay(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if d, ok := retryAfter(apiErr.Header, time.Now()); ok {
+			return d
+		}
+		if apiErr.StatusCode == http.StatusTooManyRequests {
+			if d, ok := rateLimitReset(apiErr.Header, time.Now()); ok {
+				return d
+			}
+		}
+	}
+
+	backoff := r.policy.BaseDelay << (attempt - 1)
+	if backoff <= 0 || (r.policy.MaxDelay > 0 && backoff > r.policy.MaxDelay) {
+		backoff = r.policy.MaxDelay
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	// Equal jitter: wait between half and the full backoff
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(backoff-half)+1))
+}
+
+// retryAfter extracts the delay requested by the server through
+// retry-after-ms or Retry-After (seconds or HTTP date)
+func retryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	if header == nil {
+		return 0, false
+	}
+
+	if value := header.Get("retry-after-ms"); value != "" {
+		if ms, err := strconv.ParseFloat(value, 64); err == nil && ms >= 0 {
+			return time.Duration(ms * float64(time.Millisecond)), true
+		}
+	}
+
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+			return time.Duration(seconds * float64(time.Second)), true
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return max(date.Sub(now), 0), true
+		}
+	}
+	return 0, false
+}
+
+// rateLimitReset extracts when the rate limit window resets from
+// x-ratelimit-reset (unix time or seconds) or the longer of the
+// x-ratelimit-reset-requests/-tokens durations
+func rateLimitReset(header http.Header, now time.Time) (time.Duration, bool) {
+	if header == nil {
+		return 0, false
+	}
+
+	if value := header.Get("x-ratelimit-reset"); value != "" {
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+			// Large values are a unix timestamp, small ones a relative delay
+			if seconds > 1e9 {
+				return max(time.Unix(int64(seconds), 0).Sub(now), 0), true
+			}
+			return time.Duration(seconds * float64(time.Second)), true
+		}
+	}
+
+	var longest time.Duration
+	found := false
+	for _, key := range []string{"x-ratelimit-reset-requests", "x-ratelimit-reset-tokens"} {
+		if d, err := time.ParseDuration(header.Get(key)); err == nil && d >= 0 {
+			longest = max(longest, d)
+			found = true
+		}
+	}
+	return longest, found
+}
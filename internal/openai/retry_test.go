@@ -0,0 +1,146 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+// sequenceProvider returns the queued errors in order, then succeeds
+type sequenceProvider struct {
+	errs  []error
+	calls int
+}
+
+func (s *sequenceProvider) CreateChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	s.calls++
+	if s.calls <= len(s.errs) {
+		return nil, s.errs[s.calls-1]
+	}
+	return &ChatCompletionResponse{Content: "ok"}, nil
+}
+
+func rateLimitError(header http.Header) error {
+	return &APIError{Provider: "Test", StatusCode: http.StatusTooManyRequests, Header: header, Message: "slow down"}
+}
+
+func TestRetryProvider(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	policy := RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantErr   bool
+		wantCalls int
+	}{
+		{"success on first attempt", nil, false, 1},
+		{"retries rate limit", []error{rateLimitError(nil)}, false, 2},
+		{"retries server error and transport error", []error{
+			&APIError{Provider: "Test", StatusCode: http.StatusBadGateway},
+			errors.New("connection reset"),
+		}, false, 3},
+		{"gives up after max retries", []error{rateLimitError(nil), rateLimitError(nil), rateLimitError(nil)}, true, 3},
+		{"does not retry client errors", []error{&APIError{Provider: "Test", StatusCode: http.StatusBadRequest}}, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &sequenceProvider{errs: tt.errs}
+			provider := NewRetryProvider(inner, policy, logger)
+
+			_, err := provider.CreateChatCompletion(context.Background(), ChatCompletionRequest{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CreateChatCompletion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if inner.calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", inner.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryProvider_DelayExceedsTimeout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	header := http.Header{"Retry-After": []string{"60"}}
+	inner := &sequenceProvider{errs: []error{rateLimitError(header)}}
+	provider := NewRetryProvider(inner, RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond}, logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err := provider.CreateChatCompletion(ctx, ChatCompletionRequest{})
+	if err == nil {
+		t.Fatal("Expected error when Retry-After exceeds the timeout, got nil")
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("Should fail immediately instead of waiting for the timeout")
+	}
+	if inner.calls != 1 {
+		t.Errorf("calls = %d, want 1", inner.calls)
+	}
+}
+
+func TestRetryProvider_Backoff(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	provider := NewRetryProvider(nil, RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}, logger)
+	err := errors.New("connection reset")
+
+	tests := []struct {
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{1, 500 * time.Millisecond, time.Second},
+		{2, time.Second, 2 * time.Second},
+		{3, 2 * time.Second, 4 * time.Second},
+		{4, 2500 * time.Millisecond, 5 * time.Second},
+		{10, 2500 * time.Millisecond, 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		delay := provider.delay(tt.attempt, err)
+		if delay < tt.min || delay > tt.max {
+			t.Errorf("delay(%d) = %s, want between %s and %s", tt.attempt, delay, tt.min, tt.max)
+		}
+	}
+}
+
+func TestRetryAfterHeaders(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+		found  bool
+	}{
+		{"none", http.Header{}, 0, false},
+		{"retry-after-ms", http.Header{"Retry-After-Ms": []string{"1500"}}, 1500 * time.Millisecond, true},
+		{"retry-after seconds", http.Header{"Retry-After": []string{"7"}}, 7 * time.Second, true},
+		{"retry-after date", http.Header{"Retry-After": []string{"Wed, 01 Jan 2025 12:00:30 GMT"}}, 30 * time.Second, true},
+		{"reset unix time", http.Header{"X-Ratelimit-Reset": []string{"1735732810"}}, 10 * time.Second, true},
+		{"reset seconds", http.Header{"X-Ratelimit-Reset": []string{"3"}}, 3 * time.Second, true},
+		{"reset durations", http.Header{
+			"X-Ratelimit-Reset-Requests": []string{"1s"},
+			"X-Ratelimit-Reset-Tokens":   []string{"6m0s"},
+		}, 6 * time.Minute, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := retryAfter(tt.header, now)
+			if !found {
+				got, found = rateLimitReset(tt.header, now)
+			}
+			if found != tt.found || got != tt.want {
+				t.Errorf("delay = %s (found %v), want %s (found %v)", got, found, tt.want, tt.found)
+			}
+		})
+	}
+}
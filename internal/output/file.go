@@ -0,0 +1,44 @@
+package output
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// FileWriter writes the encoded response to a file
+type FileWriter struct {
+	path   string
+	encode encodeFunc
+	logger *slog.Logger
+}
+
+// NewFileWriter creates a writer that saves the response to path
+func NewFileWriter(path string, encode encodeFunc, logger *slog.Logger) *FileWriter {
+	return &FileWriter{
+		path:   path,
+		encode: encode,
+		logger: logger,
+	}
+}
+
+// Write encodes the result and saves it, creating parent directories as needed
+func (w *FileWriter) Write(result *Result) error {
+	data, err := w.encode(result)
+	if err != nil {
+		return fmt.Errorf("error encoding output: %w", err)
+	}
+
+	if dir := filepath.Dir(w.path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("error creating output directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(w.path, data, 0644); err != nil {
+		return fmt.Errorf("error writing output file: %w", err)
+	}
+
+	w.logger.Info("response saved to file", "path", w.path, "size_bytes", len(data))
+	return nil
+}
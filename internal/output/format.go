@@ -0,0 +1,136 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/gate"
+)
+
+// encodeFunc renders a result into the bytes written to an output file
+type encodeFunc func(result *Result) ([]byte, error)
+
+var encoders = map[string]encodeFunc{
+	FormatText:     encodeText,
+	FormatJSON:     encodeJSON,
+	FormatMarkdown: encodeMarkdown,
+	FormatSARIF:    encodeSARIF,
+	FormatJUnit:    encodeJUnit,
+}
+
+// encodeText returns the raw response content
+func encodeText(result *Result) ([]byte, error) {
+	return []byte(result.Content), nil
+}
+
+// jsonUsage is the JSON representation of token usage
+type jsonUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// jsonBuild is the JSON representation of the build metadata
+type jsonBuild struct {
+	Repo      string `json:"repo"`
+	Branch    string `json:"branch"`
+	CommitSHA string `json:"commit_sha"`
+	Number    string `json:"number"`
+}
+
+// jsonResult is the versioned JSON envelope written for downstream steps.
+// Bump SchemaVersion whenever a field is renamed or removed.
+type jsonResult struct {
+	SchemaVersion  string        `json:"schema_version"`
+	Content        string        `json:"content"`
+	Model          string        `json:"model"`
+	RequestedModel string        `json:"requested_model"`
+	FinishReason   string        `json:"finish_reason"`
+	Usage          jsonUsage     `json:"usage"`
+	DurationMS     int64         `json:"duration_ms"`
+	Build          jsonBuild     `json:"build"`
+	Verdict        *gate.Verdict `json:"verdict,omitempty"`
+}
+
+// SchemaVersion is the version of the JSON envelope
+const SchemaVersion = "1"
+
+// encodeJSON wraps the response in a versioned JSON envelope
+func encodeJSON(result *Result) ([]byte, error) {
+	data, err := json.MarshalIndent(jsonResult{
+		SchemaVersion:  SchemaVersion,
+		Content:        result.Content,
+		Model:          result.Model,
+		RequestedModel: result.RequestedModel,
+		FinishReason:   result.FinishReason,
+		Usage: jsonUsage{
+			PromptTokens:     result.Usage.PromptTokens,
+			CompletionTokens: result.Usage.CompletionTokens,
+			TotalTokens:      result.Usage.TotalTokens,
+		},
+		DurationMS: result.Duration.Milliseconds(),
+		Build: jsonBuild{
+			Repo:      result.Build.Repo,
+			Branch:    result.Build.Branch,
+			CommitSHA: result.Build.CommitSHA,
+			Number:    result.Build.Number,
+		},
+		Verdict: result.Verdict,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// encodeMarkdown renders the response as a markdown report
+func encodeMarkdown(result *Result) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("# OpenAI Response\n\n")
+	if result.Model != "" {
+		fmt.Fprintf(&buf, "**Model:** `%s`\n\n", result.Model)
+	}
+	if result.Verdict != nil {
+		writeVerdictMarkdown(&buf, result.Verdict)
+	} else {
+		buf.WriteString(result.Content)
+	}
+	buf.WriteString("\n\n## Token Usage\n\n")
+	buf.WriteString("| Prompt | Completion | Total |\n")
+	buf.WriteString("| ------ | ---------- | ----- |\n")
+	fmt.Fprintf(&buf, "| %d | %d | %d |\n",
+		result.Usage.PromptTokens, result.Usage.CompletionTokens, result.Usage.TotalTokens)
+	return buf.Bytes(), nil
+}
+
+// writeVerdictMarkdown renders a gate verdict as a summary and findings table
+func writeVerdictMarkdown(buf *bytes.Buffer, verdict *gate.Verdict) {
+	fmt.Fprintf(buf, "**Verdict:** %s\n\n", strings.ToUpper(verdict.Verdict))
+	if verdict.Summary != "" {
+		buf.WriteString(verdict.Summary)
+		buf.WriteString("\n\n")
+	}
+	if len(verdict.Findings) == 0 {
+		buf.WriteString("No findings.")
+		return
+	}
+
+	buf.WriteString("## Findings\n\n")
+	writeFindingsTable(buf, verdict.Findings)
+}
+
+// writeFindingsTable renders findings as a markdown table
+func writeFindingsTable(buf *bytes.Buffer, findings []gate.Finding) {
+	buf.WriteString("| Severity | Location | Finding |\n")
+	buf.WriteString("| -------- | -------- | ------- |\n")
+	for _, f := range findings {
+		location := f.Path
+		if location != "" && f.Line > 0 {
+			location = fmt.Sprintf("%s:%d", f.Path, f.Line)
+		}
+		message := strings.ReplaceAll(f.Message, "\n", " ")
+		fmt.Fprintf(buf, "| %s | %s | **%s** %s |\n", f.Severity, location, f.Title, message)
+	}
+}
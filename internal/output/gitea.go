@@ -0,0 +1,71 @@
+package output
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/config"
+)
+
+// GiteaWriter posts the response as a pull request comment on Gitea or
+// Forgejo through the issue comments API. The comment is updated on later
+// builds instead of adding a new one each time.
+type GiteaWriter struct {
+	thread *commentThread
+	repo   string
+	number string
+	key    string
+	review bool
+	logger *slog.Logger
+}
+
+// NewGiteaWriter creates a writer for the pull request of the build. The
+// API URL is the /api/v1 endpoint of the server.
+func NewGiteaWriter(cfg config.Publish, build config.Build, logger *slog.Logger) *GiteaWriter {
+	header := http.Header{}
+	header.Set("Authorization", "token "+cfg.Token)
+	header.Set("Accept", "application/json")
+
+	client := newAPIClient("Gitea", strings.TrimSuffix(cfg.APIURL, "/"), header)
+	return &GiteaWriter{
+		thread: &commentThread{
+			client:       client,
+			path:         fmt.Sprintf("/repos/%s/issues/%s/comments", build.Repo, build.PullRequest),
+			pageSize:     "limit=50",
+			updateMethod: http.MethodPatch,
+			updatePath: func(id int64) string {
+				return fmt.Sprintf("/repos/%s/issues/comments/%d", build.Repo, id)
+			},
+		},
+		repo:   build.Repo,
+		number: build.PullRequest,
+		key:    cfg.Key,
+		review: cfg.Review,
+		logger: logger,
+	}
+}
+
+// Write creates the comment, or updates the comment left by an earlier
+// build. Gate findings are part of the comment since inline comments are
+// not supported.
+func (w *GiteaWriter) Write(result *Result) error {
+	if w.number == "" {
+		w.logger.Warn("not a pull request build, skipping publishing", "target", TargetGitea)
+		return nil
+	}
+	if w.repo == "" {
+		return fmt.Errorf("DRONE_REPO is required to publish to gitea")
+	}
+	if w.review {
+		w.logger.Warn("inline review comments are not supported on gitea, publishing a comment")
+	}
+
+	id, updated, err := w.thread.upsert(w.key, commentBody(result, w.key))
+	if err != nil {
+		return err
+	}
+	w.logger.Info("pull request comment published", "repo", w.repo, "pull_request", w.number, "comment_id", id, "updated", updated)
+	return nil
+}
@@ -0,0 +1,69 @@
+package output
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/config"
+)
+
+func TestGiteaWriter(t *testing.T) {
+	var comments []comment
+	var patched bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token gitea-token" {
+			http.Error(w, `{"message": "token is required"}`, http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/repos/org/repo/issues/5/comments":
+			if r.URL.Query().Get("limit") == "" {
+				t.Error("Expected the limit page size parameter")
+			}
+			json.NewEncoder(w).Encode(comments)
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/repos/org/repo/issues/5/comments":
+			var c comment
+			json.NewDecoder(r.Body).Decode(&c)
+			c.ID = 42
+			comments = append(comments, c)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(c)
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/v1/repos/org/repo/issues/comments/42":
+			json.NewDecoder(r.Body).Decode(&comments[0])
+			patched = true
+			json.NewEncoder(w).Encode(comments[0])
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	w := NewGiteaWriter(
+		config.Publish{Target: "gitea", Token: "gitea-token", APIURL: server.URL + "/api/v1", Key: "review"},
+		config.Build{Repo: "org/repo", PullRequest: "5"},
+		logger,
+	)
+
+	result := testResult()
+	if err := w.Write(result); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	result.Content = "Second run"
+	if err := w.Write(result); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if len(comments) != 1 || !patched {
+		t.Fatalf("Expected one comment updated in place, got %d comments", len(comments))
+	}
+	if !strings.HasPrefix(comments[0].Body, marker("review")) || !strings.Contains(comments[0].Body, "Second run") {
+		t.Errorf("Comment body = %q", comments[0].Body)
+	}
+}
@@ -0,0 +1,211 @@
+package output
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/config"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/diff"
+)
+
+// DefaultGitHubAPIURL is the API of github.com. GitHub Enterprise Server
+// serves the API at https://HOST/api/v3.
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// GitHubWriter posts the response as a pull request comment. The comment is
+// updated on later builds instead of adding a new one each time. In review
+// mode the findings of a gate verdict are posted as a pull request review
+// with inline comments instead.
+type GitHubWriter struct {
+	client *apiClient
+	thread *commentThread
+	repo   string
+	number string
+	commit string
+	key    string
+	review bool
+	logger *slog.Logger
+}
+
+// githubFile is a changed file of a pull request returned by the GitHub API
+type githubFile struct {
+	Filename string `json:"filename"`
+	Patch    string `json:"patch"`
+}
+
+// githubReviewComment is an inline comment of a pull request review
+type githubReviewComment struct {
+	ID   int64  `json:"id,omitempty"`
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	Side string `json:"side"`
+	Body string `json:"body"`
+}
+
+// githubReview is a pull request review submitted to the GitHub API
+type githubReview struct {
+	ID       int64                 `json:"id,omitempty"`
+	CommitID string                `json:"commit_id,omitempty"`
+	Body     string                `json:"body"`
+	Event    string                `json:"event"`
+	Comments []githubReviewComment `json:"comments"`
+}
+
+// NewGitHubWriter creates a writer for the pull request of the build
+func NewGitHubWriter(cfg config.Publish, build config.Build, logger *slog.Logger) *GitHubWriter {
+	baseURL := cfg.APIURL
+	if baseURL == "" {
+		baseURL = DefaultGitHubAPIURL
+	}
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+cfg.Token)
+	header.Set("Accept", "application/vnd.github+json")
+	header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	client := newAPIClient("GitHub", strings.TrimSuffix(baseURL, "/"), header)
+	return &GitHubWriter{
+		client: client,
+		thread: &commentThread{
+			client:       client,
+			path:         fmt.Sprintf("/repos/%s/issues/%s/comments", build.Repo, build.PullRequest),
+			pageSize:     "per_page=100",
+			updateMethod: http.MethodPatch,
+			updatePath: func(id int64) string {
+				return fmt.Sprintf("/repos/%s/issues/comments/%d", build.Repo, id)
+			},
+		},
+		repo:   build.Repo,
+		number: build.PullRequest,
+		commit: build.CommitSHA,
+		key:    cfg.Key,
+		review: cfg.Review,
+		logger: logger,
+	}
+}
+
+// Write creates the comment, or updates the comment left by an earlier build
+func (w *GitHubWriter) Write(result *Result) error {
+	if w.number == "" {
+		w.logger.Warn("not a pull request build, skipping publishing", "target", TargetGitHub)
+		return nil
+	}
+	if w.repo == "" {
+		return fmt.Errorf("DRONE_REPO is required to publish to github")
+	}
+
+	if w.review && result.Verdict != nil {
+		return w.writeReview(result)
+	}
+
+	id, updated, err := w.thread.upsert(w.key, commentBody(result, w.key))
+	if err != nil {
+		return err
+	}
+	w.logger.Info("pull request comment published", "repo", w.repo, "pull_request", w.number, "comment_id", id, "updated", updated)
+	return nil
+}
+
+// writeReview submits the findings as a single review. Findings on lines
+// outside the diff cannot be commented inline, so they are listed in the
+// review summary. Submitted reviews cannot be deleted, so the inline
+// comments of the review left by an earlier build are removed instead.
+func (w *GitHubWriter) writeReview(result *Result) error {
+	lines, err := w.diffLines()
+	if err != nil {
+		return err
+	}
+	previous, err := w.previousReviews()
+	if err != nil {
+		return err
+	}
+	inline, outside := splitFindings(result.Verdict.Findings, lines)
+
+	review := githubReview{
+		CommitID: w.commit,
+		Body:     reviewBody(result, w.key, len(inline), outside),
+		Event:    "COMMENT",
+		Comments: []githubReviewComment{},
+	}
+	for _, f := range inline {
+		review.Comments = append(review.Comments, githubReviewComment{
+			Path: f.Path,
+			Line: f.Line,
+			Side: "RIGHT",
+			Body: findingComment(f),
+		})
+	}
+
+	path := fmt.Sprintf("/repos/%s/pulls/%s/reviews", w.repo, w.number)
+	if _, err := w.client.do(http.MethodPost, path, review, nil); err != nil {
+		return fmt.Errorf("error submitting pull request review: %w", err)
+	}
+	for _, old := range previous {
+		if err := w.retireReview(old.ID); err != nil {
+			return err
+		}
+	}
+	w.logger.Info("pull request review submitted",
+		"repo", w.repo,
+		"pull_request", w.number,
+		"inline_comments", len(inline),
+		"summary_findings", len(outside),
+		"replaced_reviews", len(previous),
+	)
+	return nil
+}
+
+// previousReviews returns the reviews of the step left by earlier builds
+func (w *GitHubWriter) previousReviews() ([]githubReview, error) {
+	reviews, err := listPages[githubReview](w.client, fmt.Sprintf("/repos/%s/pulls/%s/reviews", w.repo, w.number))
+	if err != nil {
+		return nil, fmt.Errorf("error listing pull request reviews: %w", err)
+	}
+	var previous []githubReview
+	for _, r := range reviews {
+		if strings.HasPrefix(r.Body, marker(w.key)) {
+			previous = append(previous, r)
+		}
+	}
+	return previous, nil
+}
+
+// retireReview deletes the inline comments of an outdated review and
+// replaces its body, dropping the marker so later builds skip it
+func (w *GitHubWriter) retireReview(id int64) error {
+	comments, err := listPages[githubReviewComment](w.client, fmt.Sprintf("/repos/%s/pulls/%s/reviews/%d/comments", w.repo, w.number, id))
+	if err != nil {
+		return fmt.Errorf("error listing review comments: %w", err)
+	}
+	for _, c := range comments {
+		if _, err := w.client.do(http.MethodDelete, fmt.Sprintf("/repos/%s/pulls/comments/%d", w.repo, c.ID), nil, nil); err != nil {
+			return fmt.Errorf("error deleting review comment: %w", err)
+		}
+	}
+	path := fmt.Sprintf("/repos/%s/pulls/%s/reviews/%d", w.repo, w.number, id)
+	if _, err := w.client.do(http.MethodPut, path, map[string]string{"body": "_Superseded by a newer review._"}, nil); err != nil {
+		return fmt.Errorf("error updating pull request review: %w", err)
+	}
+	return nil
+}
+
+// diffLines returns the commentable lines of each file changed by the pull
+// request
+func (w *GitHubWriter) diffLines() (map[string]map[int]bool, error) {
+	lines := make(map[string]map[int]bool)
+	for page := 1; ; page++ {
+		var files []githubFile
+		path := fmt.Sprintf("/repos/%s/pulls/%s/files?per_page=100&page=%d", w.repo, w.number, page)
+		header, err := w.client.do(http.MethodGet, path, nil, &files)
+		if err != nil {
+			return nil, fmt.Errorf("error listing pull request files: %w", err)
+		}
+		for _, f := range files {
+			lines[f.Filename] = diff.HunkLines(f.Patch)
+		}
+		if !hasNextPage(header) {
+			return lines, nil
+		}
+	}
+}
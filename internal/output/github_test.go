@@ -0,0 +1,305 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/config"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/gate"
+)
+
+// fakeGitHub serves the issue comment and review endpoints of one pull
+// request, one comment per page to exercise pagination
+type fakeGitHub struct {
+	t        *testing.T
+	mu       sync.Mutex
+	comments []comment
+	nextID   int64
+	patches  int
+	files    []githubFile
+	reviews  []githubReview
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+		http.Error(w, `{"message": "Bad credentials"}`, http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/octocat/hello-world/issues/7/comments":
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 1 {
+			page = 1
+		}
+		var comments []comment
+		if page <= len(f.comments) {
+			comments = f.comments[page-1 : page]
+		}
+		if page < len(f.comments) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s?page=%d>; rel="next"`, r.URL.Path, page+1))
+		}
+		json.NewEncoder(w).Encode(comments)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v3/repos/octocat/hello-world/issues/7/comments":
+		var body comment
+		json.NewDecoder(r.Body).Decode(&body)
+		f.nextID++
+		body.ID = f.nextID
+		f.comments = append(f.comments, body)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(body)
+	case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, "/api/v3/repos/octocat/hello-world/issues/comments/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/v3/repos/octocat/hello-world/issues/comments/"), 10, 64)
+		var body comment
+		json.NewDecoder(r.Body).Decode(&body)
+		for i := range f.comments {
+			if f.comments[i].ID == id {
+				f.comments[i].Body = body.Body
+				f.patches++
+				json.NewEncoder(w).Encode(f.comments[i])
+				return
+			}
+		}
+		http.NotFound(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/octocat/hello-world/pulls/7/files":
+		json.NewEncoder(w).Encode(f.files)
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/octocat/hello-world/pulls/7/reviews":
+		json.NewEncoder(w).Encode(f.reviews)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v3/repos/octocat/hello-world/pulls/7/reviews":
+		var review githubReview
+		json.NewDecoder(r.Body).Decode(&review)
+		f.nextID++
+		review.ID = f.nextID
+		for i := range review.Comments {
+			f.nextID++
+			review.Comments[i].ID = f.nextID
+		}
+		f.reviews = append(f.reviews, review)
+		json.NewEncoder(w).Encode(review)
+	case strings.HasPrefix(r.URL.Path, "/api/v3/repos/octocat/hello-world/pulls/7/reviews/"):
+		rest := strings.TrimPrefix(r.URL.Path, "/api/v3/repos/octocat/hello-world/pulls/7/reviews/")
+		id, _ := strconv.ParseInt(strings.TrimSuffix(rest, "/comments"), 10, 64)
+		for i := range f.reviews {
+			if f.reviews[i].ID != id {
+				continue
+			}
+			if r.Method == http.MethodPut {
+				var body githubReview
+				json.NewDecoder(r.Body).Decode(&body)
+				f.reviews[i].Body = body.Body
+			}
+			if strings.HasSuffix(rest, "/comments") {
+				json.NewEncoder(w).Encode(f.reviews[i].Comments)
+				return
+			}
+			json.NewEncoder(w).Encode(f.reviews[i])
+			return
+		}
+		http.NotFound(w, r)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/api/v3/repos/octocat/hello-world/pulls/comments/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/v3/repos/octocat/hello-world/pulls/comments/"), 10, 64)
+		for i := range f.reviews {
+			for j, c := range f.reviews[i].Comments {
+				if c.ID == id {
+					f.reviews[i].Comments = append(f.reviews[i].Comments[:j], f.reviews[i].Comments[j+1:]...)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+		}
+		http.NotFound(w, r)
+	default:
+		f.t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		http.NotFound(w, r)
+	}
+}
+
+func newGitHubTest(t *testing.T, key string) (*fakeGitHub, *GitHubWriter) {
+	fake := &fakeGitHub{t: t}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	w := NewGitHubWriter(
+		config.Publish{Target: "github", Token: "test-token", APIURL: server.URL + "/api/v3/", Key: key},
+		config.Build{Repo: "octocat/hello-world", PullRequest: "7", CommitSHA: "abc123"},
+		logger,
+	)
+	return fake, w
+}
+
+func TestGitHubWriter_CreatesAndUpdates(t *testing.T) {
+	fake, w := newGitHubTest(t, "review")
+	fake.comments = []comment{
+		{ID: 100, Body: "LGTM"},
+		{ID: 101, Body: marker("security") + "\nother step"},
+	}
+
+	result := testResult()
+	if err := w.Write(result); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if len(fake.comments) != 3 {
+		t.Fatalf("Expected a new comment, got %d comments", len(fake.comments))
+	}
+	created := fake.comments[2]
+	if !strings.HasPrefix(created.Body, marker("review")) || !strings.Contains(created.Body, "Hello from the model") {
+		t.Errorf("Comment body = %q, want the marker and the response", created.Body)
+	}
+
+	result.Content = "Second run"
+	if err := w.Write(result); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if len(fake.comments) != 3 || fake.patches != 1 {
+		t.Fatalf("Expected the comment to be updated, got %d comments and %d updates", len(fake.comments), fake.patches)
+	}
+	if !strings.Contains(fake.comments[2].Body, "Second run") {
+		t.Errorf("Updated body = %q", fake.comments[2].Body)
+	}
+	if fake.comments[1].Body != marker("security")+"\nother step" {
+		t.Error("Comments of other steps should not be touched")
+	}
+}
+
+func TestGitHubWriter_Review(t *testing.T) {
+	fake, w := newGitHubTest(t, "review")
+	w.review = true
+	fake.files = []githubFile{
+		{Filename: "main.go", Patch: "@@ -10,3 +10,4 @@ func main() {\n \ta()\n+\tb()\n \tc()\n \td()"},
+		{Filename: "logo.png"},
+	}
+
+	result := testResult()
+	result.Verdict = &gate.Verdict{
+		Verdict: "fail",
+		Summary: "Two problems",
+		Findings: []gate.Finding{
+			{Severity: "high", Title: "Unchecked error", Message: "b() can fail", Path: "./main.go", Line: 11},
+			{Severity: "medium", Title: "Stale doc", Message: "Update the docs", Path: "main.go", Line: 2},
+			{Severity: "low", Title: "General", Message: "Add tests"},
+		},
+	}
+	if err := w.Write(result); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if len(fake.comments) != 0 {
+		t.Error("Review mode should not post a comment")
+	}
+	if len(fake.reviews) != 1 {
+		t.Fatalf("Expected one review, got %d", len(fake.reviews))
+	}
+	review := fake.reviews[0]
+	if review.CommitID != "abc123" || review.Event != "COMMENT" {
+		t.Errorf("Review = %+v", review)
+	}
+	if len(review.Comments) != 1 {
+		t.Fatalf("Expected one inline comment, got %+v", review.Comments)
+	}
+	comment := review.Comments[0]
+	if comment.Path != "main.go" || comment.Line != 11 || comment.Side != "RIGHT" || !strings.Contains(comment.Body, "Unchecked error") {
+		t.Errorf("Inline comment = %+v", comment)
+	}
+	for _, want := range []string{marker("review"), "**Verdict:** FAIL", "1 finding(s) commented inline.", "Findings outside the diff", "main.go:2", "Add tests"} {
+		if !strings.Contains(review.Body, want) {
+			t.Errorf("Review body should contain %q, got %q", want, review.Body)
+		}
+	}
+}
+
+func TestGitHubWriter_ReviewReplacesPrevious(t *testing.T) {
+	fake, w := newGitHubTest(t, "review")
+	w.review = true
+	fake.files = []githubFile{
+		{Filename: "main.go", Patch: "@@ -10,3 +10,4 @@ func main() {\n \ta()\n+\tb()\n \tc()\n \td()"},
+	}
+	other := githubReview{
+		ID:       1000,
+		Body:     marker("security") + "\nother step",
+		Comments: []githubReviewComment{{ID: 1001, Path: "main.go", Line: 11, Body: "other"}},
+	}
+	fake.reviews = []githubReview{other}
+
+	result := testResult()
+	result.Verdict = &gate.Verdict{
+		Verdict:  "fail",
+		Findings: []gate.Finding{{Severity: "high", Title: "Unchecked error", Message: "b() can fail", Path: "main.go", Line: 11}},
+	}
+	for i := 0; i < 2; i++ {
+		if err := w.Write(result); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	if len(fake.reviews) != 3 {
+		t.Fatalf("Expected a new review per build, got %d", len(fake.reviews))
+	}
+	first, second := fake.reviews[1], fake.reviews[2]
+	if len(first.Comments) != 0 || strings.Contains(first.Body, marker("review")) {
+		t.Errorf("Earlier review should lose its comments and marker, got %+v", first)
+	}
+	if len(second.Comments) != 1 || !strings.HasPrefix(second.Body, marker("review")) {
+		t.Errorf("Latest review = %+v", second)
+	}
+	if len(fake.reviews[0].Comments) != 1 || fake.reviews[0].Body != other.Body {
+		t.Error("Reviews of other steps should not be touched")
+	}
+}
+
+func TestGitHubWriter_ReviewWithoutVerdict(t *testing.T) {
+	fake, w := newGitHubTest(t, "review")
+	w.review = true
+
+	if err := w.Write(testResult()); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if len(fake.reviews) != 0 || len(fake.comments) != 1 {
+		t.Error("Results without a verdict should be posted as a comment")
+	}
+}
+
+func TestCleanPath(t *testing.T) {
+	tests := map[string]string{
+		"main.go":           "main.go",
+		"./pkg/util.go":     "pkg/util.go",
+		"/pkg/util.go":      "pkg/util.go",
+		".github/ci.yml":    ".github/ci.yml",
+		"pkg\\win\\file.go": "pkg/win/file.go",
+		"":                  "",
+	}
+	for in, want := range tests {
+		if got := cleanPath(in); got != want {
+			t.Errorf("cleanPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGitHubWriter_NotPullRequest(t *testing.T) {
+	_, w := newGitHubTest(t, "review")
+	w.number = ""
+
+	if err := w.Write(testResult()); err != nil {
+		t.Errorf("Write() should skip builds without a pull request, got %v", err)
+	}
+}
+
+func TestGitHubWriter_APIError(t *testing.T) {
+	_, w := newGitHubTest(t, "review")
+	w.client.header.Set("Authorization", "Bearer wrong")
+
+	err := w.Write(testResult())
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "Bad credentials") {
+		t.Errorf("Write() error = %v, want the status and message", err)
+	}
+}
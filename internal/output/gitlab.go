@@ -0,0 +1,207 @@
+package output
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/config"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/diff"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/gate"
+)
+
+// DefaultGitLabAPIURL is the API of gitlab.com. Self-managed instances serve
+// the API at https://HOST/api/v4.
+const DefaultGitLabAPIURL = "https://gitlab.com/api/v4"
+
+// GitLabWriter posts the response as a merge request note. The note is
+// updated on later builds instead of adding a new one each time. In review
+// mode the findings of a gate verdict are posted as diff discussions on
+// their lines, with the verdict and remaining findings in the note.
+type GitLabWriter struct {
+	client  *apiClient
+	thread  *commentThread
+	project string // URL encoded project path
+	iid     string
+	key     string
+	review  bool
+	logger  *slog.Logger
+}
+
+// gitlabDiffRefs are the commits a diff position refers to
+type gitlabDiffRefs struct {
+	BaseSHA  string `json:"base_sha"`
+	HeadSHA  string `json:"head_sha"`
+	StartSHA string `json:"start_sha"`
+}
+
+// gitlabDiff is a changed file of a merge request
+type gitlabDiff struct {
+	NewPath string `json:"new_path"`
+	Diff    string `json:"diff"`
+}
+
+// gitlabPosition places a discussion on a line of the new version of a file
+type gitlabPosition struct {
+	PositionType string `json:"position_type"`
+	BaseSHA      string `json:"base_sha"`
+	HeadSHA      string `json:"head_sha"`
+	StartSHA     string `json:"start_sha"`
+	NewPath      string `json:"new_path"`
+	NewLine      int    `json:"new_line"`
+}
+
+// gitlabDiscussion is a merge request discussion, started by its first note
+type gitlabDiscussion struct {
+	Notes []struct {
+		Body     string          `json:"body"`
+		Position *gitlabPosition `json:"position"`
+	} `json:"notes"`
+}
+
+// NewGitLabWriter creates a writer for the merge request of the build
+func NewGitLabWriter(cfg config.Publish, build config.Build, logger *slog.Logger) *GitLabWriter {
+	baseURL := cfg.APIURL
+	if baseURL == "" {
+		baseURL = DefaultGitLabAPIURL
+	}
+	header := http.Header{}
+	header.Set("PRIVATE-TOKEN", cfg.Token)
+
+	client := newAPIClient("GitLab", strings.TrimSuffix(baseURL, "/"), header)
+	project := url.PathEscape(build.Repo)
+	notes := fmt.Sprintf("/projects/%s/merge_requests/%s/notes", project, build.PullRequest)
+	return &GitLabWriter{
+		client: client,
+		thread: &commentThread{
+			client:       client,
+			path:         notes,
+			pageSize:     "per_page=100",
+			updateMethod: http.MethodPut,
+			updatePath: func(id int64) string {
+				return fmt.Sprintf("%s/%d", notes, id)
+			},
+		},
+		project: project,
+		iid:     build.PullRequest,
+		key:     cfg.Key,
+		review:  cfg.Review,
+		logger:  logger,
+	}
+}
+
+// Write creates the note, or updates the note left by an earlier build
+func (w *GitLabWriter) Write(result *Result) error {
+	if w.iid == "" {
+		w.logger.Warn("not a merge request build, skipping publishing", "target", TargetGitLab)
+		return nil
+	}
+	if w.project == "" {
+		return fmt.Errorf("DRONE_REPO is required to publish to gitlab")
+	}
+
+	body := commentBody(result, w.key)
+	if w.review && result.Verdict != nil {
+		inline, outside, err := w.writeDiscussions(result.Verdict.Findings)
+		if err != nil {
+			return err
+		}
+		body = reviewBody(result, w.key, inline, outside)
+	}
+
+	id, updated, err := w.thread.upsert(w.key, body)
+	if err != nil {
+		return err
+	}
+	w.logger.Info("merge request note published", "project", w.project, "merge_request", w.iid, "note_id", id, "updated", updated)
+	return nil
+}
+
+// writeDiscussions starts a diff discussion for each finding on a line of
+// the merge request diff, unless an earlier build already started the same
+// discussion on that line. It returns the number of findings commented
+// inline and the findings left for the summary, including those GitLab
+// refused to place.
+func (w *GitLabWriter) writeDiscussions(findings []gate.Finding) (int, []gate.Finding, error) {
+	base := fmt.Sprintf("/projects/%s/merge_requests/%s", w.project, w.iid)
+	var mr struct {
+		DiffRefs gitlabDiffRefs `json:"diff_refs"`
+	}
+	if _, err := w.client.do(http.MethodGet, base, nil, &mr); err != nil {
+		return 0, nil, fmt.Errorf("error reading merge request: %w", err)
+	}
+
+	lines := make(map[string]map[int]bool)
+	for page := 1; ; page++ {
+		var diffs []gitlabDiff
+		header, err := w.client.do(http.MethodGet, fmt.Sprintf("%s/diffs?per_page=100&page=%d", base, page), nil, &diffs)
+		if err != nil {
+			return 0, nil, fmt.Errorf("error listing merge request diffs: %w", err)
+		}
+		for _, d := range diffs {
+			lines[d.NewPath] = diff.HunkLines(d.Diff)
+		}
+		if !hasNextPage(header) {
+			break
+		}
+	}
+
+	existing, err := w.existingDiscussions(base)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	inline, outside := splitFindings(findings, lines)
+	posted, skipped := 0, 0
+	for _, f := range inline {
+		body := findingComment(f)
+		if existing[discussionKey(f.Path, f.Line, body)] {
+			skipped++
+			continue
+		}
+		discussion := map[string]any{
+			"body": body,
+			"position": gitlabPosition{
+				PositionType: "text",
+				BaseSHA:      mr.DiffRefs.BaseSHA,
+				HeadSHA:      mr.DiffRefs.HeadSHA,
+				StartSHA:     mr.DiffRefs.StartSHA,
+				NewPath:      f.Path,
+				NewLine:      f.Line,
+			},
+		}
+		if _, err := w.client.do(http.MethodPost, base+"/discussions", discussion, nil); err != nil {
+			w.logger.Warn("diff discussion failed, adding the finding to the summary", "path", f.Path, "line", f.Line, "error", err)
+			outside = append(outside, f)
+			continue
+		}
+		posted++
+	}
+	w.logger.Info("merge request discussions created", "discussions", posted, "existing_discussions", skipped, "summary_findings", len(outside))
+	return posted + skipped, outside, nil
+}
+
+// existingDiscussions returns the keys of the diff discussions already on
+// the merge request
+func (w *GitLabWriter) existingDiscussions(base string) (map[string]bool, error) {
+	discussions, err := listPages[gitlabDiscussion](w.client, base+"/discussions")
+	if err != nil {
+		return nil, fmt.Errorf("error listing merge request discussions: %w", err)
+	}
+	existing := make(map[string]bool)
+	for _, d := range discussions {
+		if len(d.Notes) == 0 || d.Notes[0].Position == nil {
+			continue
+		}
+		first := d.Notes[0]
+		existing[discussionKey(first.Position.NewPath, first.Position.NewLine, first.Body)] = true
+	}
+	return existing, nil
+}
+
+// discussionKey identifies a discussion by its line and body
+func discussionKey(path string, line int, body string) string {
+	return fmt.Sprintf("%s:%d:%s", path, line, body)
+}
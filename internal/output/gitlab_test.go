@@ -0,0 +1,164 @@
+package output
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/config"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/gate"
+)
+
+// fakeGitLab serves the note, diff and discussion endpoints of one merge
+// request. Discussions on line 13 are refused like a stale position.
+type fakeGitLab struct {
+	t           *testing.T
+	mu          sync.Mutex
+	notes       []comment
+	updates     int
+	discussions []map[string]any
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("PRIVATE-TOKEN") != "glpat-test" {
+		http.Error(w, `{"message": "401 Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	const mr = "/api/v4/projects/group%2Fproject/merge_requests/3"
+	path := r.URL.EscapedPath()
+	switch {
+	case r.Method == http.MethodGet && path == mr:
+		w.Write([]byte(`{"iid": 3, "diff_refs": {"base_sha": "base", "head_sha": "head", "start_sha": "start"}}`))
+	case r.Method == http.MethodGet && path == mr+"/diffs":
+		w.Write([]byte(`[{"new_path": "app.rb", "diff": "@@ -10,2 +10,4 @@\n a\n+b\n+c\n d\n"}]`))
+	case r.Method == http.MethodGet && path == mr+"/notes":
+		json.NewEncoder(w).Encode(f.notes)
+	case r.Method == http.MethodPost && path == mr+"/notes":
+		var note comment
+		json.NewDecoder(r.Body).Decode(&note)
+		note.ID = int64(len(f.notes) + 1)
+		f.notes = append(f.notes, note)
+		json.NewEncoder(w).Encode(note)
+	case r.Method == http.MethodPut && strings.HasPrefix(path, mr+"/notes/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(path, mr+"/notes/"), 10, 64)
+		var note comment
+		json.NewDecoder(r.Body).Decode(&note)
+		f.notes[id-1].Body = note.Body
+		f.updates++
+		json.NewEncoder(w).Encode(f.notes[id-1])
+	case r.Method == http.MethodGet && path == mr+"/discussions":
+		discussions := []map[string]any{}
+		for _, d := range f.discussions {
+			discussions = append(discussions, map[string]any{
+				"notes": []map[string]any{{"body": d["body"], "position": d["position"]}},
+			})
+		}
+		json.NewEncoder(w).Encode(discussions)
+	case r.Method == http.MethodPost && path == mr+"/discussions":
+		var discussion map[string]any
+		json.NewDecoder(r.Body).Decode(&discussion)
+		position := discussion["position"].(map[string]any)
+		if position["new_line"] == float64(13) {
+			http.Error(w, `{"message": "400 Bad request - Note {:line_code=>[\"can't be blank\"]}"}`, http.StatusBadRequest)
+			return
+		}
+		f.discussions = append(f.discussions, discussion)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "abc"}`))
+	default:
+		f.t.Errorf("Unexpected request %s %s", r.Method, path)
+		http.NotFound(w, r)
+	}
+}
+
+func newGitLabTest(t *testing.T, review bool) (*fakeGitLab, *GitLabWriter) {
+	fake := &fakeGitLab{t: t}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	w := NewGitLabWriter(
+		config.Publish{Target: "gitlab", Token: "glpat-test", APIURL: server.URL + "/api/v4", Key: "review", Review: review},
+		config.Build{Repo: "group/project", PullRequest: "3"},
+		logger,
+	)
+	return fake, w
+}
+
+func TestGitLabWriter_Note(t *testing.T) {
+	fake, w := newGitLabTest(t, false)
+
+	result := testResult()
+	if err := w.Write(result); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	result.Content = "Second run"
+	if err := w.Write(result); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if len(fake.notes) != 1 || fake.updates != 1 {
+		t.Fatalf("Expected one note updated once, got %d notes and %d updates", len(fake.notes), fake.updates)
+	}
+	if !strings.HasPrefix(fake.notes[0].Body, marker("review")) || !strings.Contains(fake.notes[0].Body, "Second run") {
+		t.Errorf("Note body = %q", fake.notes[0].Body)
+	}
+}
+
+func TestGitLabWriter_Review(t *testing.T) {
+	fake, w := newGitLabTest(t, true)
+
+	result := testResult()
+	result.Verdict = &gate.Verdict{
+		Verdict: "warn",
+		Findings: []gate.Finding{
+			{Severity: "medium", Title: "Typo", Message: "Rename b", Path: "app.rb", Line: 11},
+			{Severity: "low", Title: "Refused", Message: "Placed in the summary", Path: "app.rb", Line: 13},
+			{Severity: "low", Title: "Outside", Message: "Not in the diff", Path: "lib.rb", Line: 1},
+		},
+	}
+	if err := w.Write(result); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if len(fake.discussions) != 1 {
+		t.Fatalf("Expected one discussion, got %d", len(fake.discussions))
+	}
+	position := fake.discussions[0]["position"].(map[string]any)
+	if position["new_path"] != "app.rb" || position["new_line"] != float64(11) || position["head_sha"] != "head" || position["position_type"] != "text" {
+		t.Errorf("Discussion position = %v", position)
+	}
+
+	if len(fake.notes) != 1 {
+		t.Fatalf("Expected the summary note, got %d notes", len(fake.notes))
+	}
+	for _, want := range []string{"**Verdict:** WARN", "1 finding(s) commented inline.", "Refused", "lib.rb:1"} {
+		if !strings.Contains(fake.notes[0].Body, want) {
+			t.Errorf("Summary should contain %q, got %q", want, fake.notes[0].Body)
+		}
+	}
+
+	// A later build does not repeat discussions that are already there
+	result.Verdict.Findings = append(result.Verdict.Findings, gate.Finding{Severity: "high", Title: "New", Message: "Found later", Path: "app.rb", Line: 12})
+	if err := w.Write(result); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if len(fake.discussions) != 2 {
+		t.Fatalf("Expected only the new finding to start a discussion, got %d discussions", len(fake.discussions))
+	}
+	if position := fake.discussions[1]["position"].(map[string]any); position["new_line"] != float64(12) {
+		t.Errorf("Second discussion position = %v", position)
+	}
+	if len(fake.notes) != 1 || !strings.Contains(fake.notes[0].Body, "2 finding(s) commented inline.") {
+		t.Errorf("Expected the summary note to be updated, got %+v", fake.notes)
+	}
+}
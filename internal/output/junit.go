@@ -0,0 +1,144 @@
+package output
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/gate"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	TestCases  []junitTestCase `xml:"testcase"`
+	SystemOut  string          `xml:"system-out"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// encodeJUnit renders the result as a JUnit XML report. In gate mode every
+// finding is a test case that fails when it is at or above the fail_on
+// severity; otherwise the prompt is a single passing test case. The
+// response is attached as the suite's system-out.
+func encodeJUnit(result *Result) ([]byte, error) {
+	elapsed := fmt.Sprintf("%.3f", result.Duration.Seconds())
+	suite := junitTestSuite{
+		Name: suiteName(result),
+		Time: elapsed,
+		Properties: []junitProperty{
+			{Name: "model", Value: result.Model},
+			{Name: "finish_reason", Value: result.FinishReason},
+			{Name: "total_tokens", Value: fmt.Sprint(result.Usage.TotalTokens)},
+		},
+		SystemOut: result.Content,
+	}
+
+	if result.Verdict == nil || len(result.Verdict.Findings) == 0 {
+		name := "prompt"
+		if result.Verdict != nil {
+			name = "verdict: " + result.Verdict.Verdict
+		}
+		suite.TestCases = append(suite.TestCases, junitTestCase{Name: name, ClassName: toolName, Time: elapsed})
+	} else {
+		suite.Properties = append(suite.Properties,
+			junitProperty{Name: "verdict", Value: result.Verdict.Verdict},
+			junitProperty{Name: "fail_on", Value: result.FailOn.String()},
+		)
+		for _, f := range result.Verdict.Findings {
+			suite.TestCases = append(suite.TestCases, findingTestCase(f, result.FailOn))
+		}
+	}
+
+	for _, tc := range suite.TestCases {
+		if tc.Failure != nil {
+			suite.Failures++
+		}
+	}
+	suite.Tests = len(suite.TestCases)
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitTestSuites{
+		Name:     toolName,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Time:     elapsed,
+		Suites:   []junitTestSuite{suite},
+	}); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// suiteName names the test suite after the repository when it is known
+func suiteName(result *Result) string {
+	if result.Build.Repo != "" {
+		return toolName + ": " + result.Build.Repo
+	}
+	return toolName
+}
+
+// findingTestCase converts a finding into a test case, failing when the
+// finding is at or above failOn. Findings are grouped by file.
+func findingTestCase(f gate.Finding, failOn gate.Severity) junitTestCase {
+	path := cleanPath(f.Path)
+	tc := junitTestCase{
+		Name:      fmt.Sprintf("[%s] %s", f.Severity, f.Title),
+		ClassName: path,
+		File:      path,
+		Line:      f.Line,
+		Time:      "0",
+	}
+	if tc.ClassName == "" {
+		tc.ClassName = toolName
+	}
+
+	detail := f.Message
+	if path != "" && f.Line > 0 {
+		detail += fmt.Sprintf("\n\nat %s:%d", path, f.Line)
+	}
+	severity, err := gate.ParseSeverity(f.Severity)
+	if err == nil && severity >= failOn {
+		tc.Failure = &junitFailure{Message: f.Title, Type: ruleID(f), Text: detail}
+	} else {
+		tc.SystemOut = detail
+	}
+	return tc
+}
@@ -0,0 +1,88 @@
+package output
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/gate"
+)
+
+func TestEncodeJUnit(t *testing.T) {
+	result := testResult()
+	result.FailOn = gate.SeverityHigh
+	result.Verdict = &gate.Verdict{
+		Verdict: "fail",
+		Findings: []gate.Finding{
+			{Severity: "critical", Rule: "sql-injection", Title: "SQL injection", Message: "Use parameters", Path: "./db/query.go", Line: 42},
+			{Severity: "low", Title: "Missing docs", Message: "Add a comment"},
+		},
+	}
+
+	data, err := encodeJUnit(result)
+	if err != nil {
+		t.Fatalf("encodeJUnit() error = %v", err)
+	}
+	if !strings.HasPrefix(string(data), xml.Header) {
+		t.Errorf("Expected an XML header, got %q", data)
+	}
+	var report junitTestSuites
+	if err := xml.Unmarshal(data, &report); err != nil {
+		t.Fatalf("JUnit output is not valid XML: %v", err)
+	}
+
+	if report.Tests != 2 || report.Failures != 1 || len(report.Suites) != 1 {
+		t.Fatalf("Report = %d tests, %d failures, %d suites", report.Tests, report.Failures, len(report.Suites))
+	}
+	suite := report.Suites[0]
+	if suite.Name != "drone-openai-plugin: octocat/hello-world" || suite.Time != "1.500" {
+		t.Errorf("Suite = %q with time %q", suite.Name, suite.Time)
+	}
+	if suite.SystemOut != "Hello from the model" {
+		t.Errorf("Expected the response as system-out, got %q", suite.SystemOut)
+	}
+
+	failed := suite.TestCases[0]
+	if failed.Name != "[critical] SQL injection" || failed.ClassName != "db/query.go" || failed.Line != 42 {
+		t.Errorf("Test case = %+v", failed)
+	}
+	if failed.Failure == nil || failed.Failure.Type != "sql-injection" || !strings.Contains(failed.Failure.Text, "at db/query.go:42") {
+		t.Errorf("Failure = %+v", failed.Failure)
+	}
+
+	passed := suite.TestCases[1]
+	if passed.Failure != nil || passed.ClassName != "drone-openai-plugin" || passed.SystemOut != "Add a comment" {
+		t.Errorf("Finding below fail_on should pass, got %+v", passed)
+	}
+}
+
+func TestEncodeJUnit_Prompt(t *testing.T) {
+	tests := []struct {
+		name    string
+		verdict *gate.Verdict
+		want    string
+	}{
+		{name: "without gate", want: "prompt"},
+		{name: "without findings", verdict: &gate.Verdict{Verdict: "pass"}, want: "verdict: pass"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := testResult()
+			result.Verdict = tt.verdict
+
+			data, err := encodeJUnit(result)
+			if err != nil {
+				t.Fatalf("encodeJUnit() error = %v", err)
+			}
+			var report junitTestSuites
+			if err := xml.Unmarshal(data, &report); err != nil {
+				t.Fatalf("JUnit output is not valid XML: %v", err)
+			}
+			cases := report.Suites[0].TestCases
+			if report.Tests != 1 || report.Failures != 0 || cases[0].Name != tt.want || cases[0].Failure != nil {
+				t.Errorf("Expected a single passing test case %q, got %+v", tt.want, cases)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/config"
+)
+
+// maxCommentSize keeps comments below the size limits of the code hosts
+const maxCommentSize = 60000
+
+// Supported publish targets
+const (
+	TargetAuto    = "auto"
+	TargetGitHub  = "github"
+	TargetGitLab  = "gitlab"
+	TargetGitea   = "gitea"
+	TargetForgejo = "forgejo"
+)
+
+// NewPublisher creates the writer that posts the response to the pull
+// request of the build. It returns nil when publishing is disabled. The
+// auto target picks the code host from the repository link, and the API
+// URL defaults to the API of the host serving the repository.
+func NewPublisher(cfg config.Publish, build config.Build, logger *slog.Logger) (Writer, error) {
+	target := cfg.Target
+	if target == TargetAuto {
+		target = DetectTarget(build)
+		if target == "" {
+			return nil, fmt.Errorf("cannot detect the code host from DRONE_REPO_LINK, set PUBLISH to github, gitlab or gitea")
+		}
+		logger.Info("publish target detected", "target", target, "repo_link", build.RepoLink)
+	}
+	if cfg.APIURL == "" && target != "" {
+		cfg.APIURL = defaultAPIURL(target, build.RepoLink)
+	}
+
+	switch target {
+	case "":
+		return nil, nil
+	case TargetGitHub:
+		return NewGitHubWriter(cfg, build, logger), nil
+	case TargetGitLab:
+		return NewGitLabWriter(cfg, build, logger), nil
+	case TargetGitea, TargetForgejo:
+		if cfg.APIURL == "" {
+			return nil, fmt.Errorf("PUBLISH_API_URL or DRONE_REPO_LINK is required to publish to %s", target)
+		}
+		return NewGiteaWriter(cfg, build, logger), nil
+	default:
+		return nil, fmt.Errorf("unsupported publish target %q", target)
+	}
+}
+
+// DetectTarget guesses the code host from the host names of the repository
+// link and the Drone server. It returns an empty string when neither names
+// a known code host.
+func DetectTarget(build config.Build) string {
+	for _, host := range []string{linkHost(build.RepoLink), strings.ToLower(build.SystemHost)} {
+		switch {
+		case host == "":
+		case strings.Contains(host, "github"):
+			return TargetGitHub
+		case strings.Contains(host, "gitlab"):
+			return TargetGitLab
+		case strings.Contains(host, "gitea"), strings.Contains(host, "forgejo"), host == "codeberg.org":
+			return TargetGitea
+		}
+	}
+	return ""
+}
+
+// linkHost returns the lower case host name of a repository link
+func linkHost(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// defaultAPIURL derives the API base URL of the target from the repository
+// link, falling back to the public service of the target
+func defaultAPIURL(target, link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		switch target {
+		case TargetGitHub:
+			return DefaultGitHubAPIURL
+		case TargetGitLab:
+			return DefaultGitLabAPIURL
+		}
+		return ""
+	}
+
+	origin := u.Scheme + "://" + u.Host
+	switch target {
+	case TargetGitHub:
+		if strings.EqualFold(u.Hostname(), "github.com") {
+			return DefaultGitHubAPIURL
+		}
+		return origin + "/api/v3"
+	case TargetGitLab:
+		return origin + "/api/v4"
+	default:
+		return origin + "/api/v1"
+	}
+}
+
+// marker is the hidden HTML comment that identifies the comment of a step
+func marker(key string) string {
+	return fmt.Sprintf("<!-- drone-openai-plugin:%s -->", key)
+}
+
+// commentBody renders the result as a markdown comment tagged with the marker
+func commentBody(result *Result, key string) string {
+	data, _ := encodeMarkdown(result)
+	body := string(data)
+	if len(body) > maxCommentSize {
+		body = truncateUTF8(body, maxCommentSize) + "\n\n… [truncated, see the build log for the full response]"
+	}
+	return marker(key) + "\n" + body
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// comment is a pull request comment as returned by the code host APIs
+type comment struct {
+	ID   int64  `json:"id"`
+	Body string `json:"body"`
+}
+
+// commentThread keeps a single comment per step on a pull request, found
+// again on later builds by its marker
+type commentThread struct {
+	client       *apiClient
+	path         string // lists and creates comments
+	pageSize     string // query parameter setting the page size
+	updateMethod string
+	updatePath   func(id int64) string
+}
+
+// upsert updates the comment of the step, or creates it when there is none
+func (t *commentThread) upsert(key, body string) (id int64, updated bool, err error) {
+	existing, err := t.find(key)
+	if err != nil {
+		return 0, false, err
+	}
+	payload := map[string]string{"body": body}
+	if existing != nil {
+		if _, err := t.client.do(t.updateMethod, t.updatePath(existing.ID), payload, nil); err != nil {
+			return 0, false, fmt.Errorf("error updating pull request comment: %w", err)
+		}
+		return existing.ID, true, nil
+	}
+
+	var created comment
+	if _, err := t.client.do(http.MethodPost, t.path, payload, &created); err != nil {
+		return 0, false, fmt.Errorf("error creating pull request comment: %w", err)
+	}
+	return created.ID, false, nil
+}
+
+// find returns the comment carrying the marker of key, if any. All three
+// code hosts announce further pages in the Link header.
+func (t *commentThread) find(key string) (*comment, error) {
+	tag := marker(key)
+	for page := 1; ; page++ {
+		var comments []comment
+		path := fmt.Sprintf("%s?%s&page=%d", t.path, t.pageSize, page)
+		header, err := t.client.do(http.MethodGet, path, nil, &comments)
+		if err != nil {
+			return nil, fmt.Errorf("error listing pull request comments: %w", err)
+		}
+		for i := range comments {
+			if strings.HasPrefix(comments[i].Body, tag) {
+				return &comments[i], nil
+			}
+		}
+		if !hasNextPage(header) {
+			return nil, nil
+		}
+	}
+}
+
+// listPages fetches every page of a list endpoint that takes per_page and
+// page parameters
+func listPages[T any](client *apiClient, path string) ([]T, error) {
+	var all []T
+	for page := 1; ; page++ {
+		var items []T
+		header, err := client.do(http.MethodGet, fmt.Sprintf("%s?per_page=100&page=%d", path, page), nil, &items)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		if !hasNextPage(header) {
+			return all, nil
+		}
+	}
+}
+
+// hasNextPage reports whether a paginated response has more pages
+func hasNextPage(header http.Header) bool {
+	return strings.Contains(header.Get("Link"), `rel="next"`)
+}
+
+// apiClient sends JSON requests to the REST API of a code host
+type apiClient struct {
+	name       string // name of the code host used in errors
+	baseURL    string
+	header     http.Header
+	httpClient *http.Client
+}
+
+func newAPIClient(name, baseURL string, header http.Header) *apiClient {
+	return &apiClient{
+		name:       name,
+		baseURL:    baseURL,
+		header:     header,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// do sends a request with an optional JSON body and decodes the JSON
+// response into out when it is not nil
+func (c *apiClient) do(method, path string, body, out any) (http.Header, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", "drone-openai-plugin")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s API error: %w", c.name, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s response: %w", c.name, err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s API error: %s %s: %d %s", c.name, method, path, resp.StatusCode, bytes.TrimSpace(data))
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return nil, fmt.Errorf("error decoding %s response: %w", c.name, err)
+		}
+	}
+	return resp.Header, nil
+}
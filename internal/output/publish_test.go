@@ -0,0 +1,118 @@
+package output
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/config"
+)
+
+func TestCommentBody_Truncates(t *testing.T) {
+	result := testResult()
+	result.Content = strings.Repeat("x", maxCommentSize+100)
+
+	body := commentBody(result, "review")
+	if len(body) > maxCommentSize+200 || !strings.Contains(body, "[truncated") {
+		t.Errorf("Comment body should be truncated, got %d bytes", len(body))
+	}
+
+	// Multi-byte characters straddling the limit are dropped whole
+	for _, prefix := range []string{"", "a", "ab"} {
+		result.Content = prefix + strings.Repeat("€", maxCommentSize)
+		if body := commentBody(result, "review"); !utf8.ValidString(body) {
+			t.Errorf("Truncated comment body with prefix %q is not valid UTF-8", prefix)
+		}
+	}
+}
+
+func TestNewPublisher(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tests := []struct {
+		name    string
+		cfg     config.Publish
+		build   config.Build
+		want    string // writer type
+		wantURL string
+		wantErr bool
+	}{
+		{name: "disabled", cfg: config.Publish{}},
+		{name: "github", cfg: config.Publish{Target: "github"}, want: "github", wantURL: DefaultGitHubAPIURL},
+		{
+			name:    "github enterprise from repo link",
+			cfg:     config.Publish{Target: "github"},
+			build:   config.Build{RepoLink: "https://github.example.com/octocat/hello-world"},
+			want:    "github",
+			wantURL: "https://github.example.com/api/v3",
+		},
+		{
+			name:    "explicit api url",
+			cfg:     config.Publish{Target: "github", APIURL: "http://localhost:8080"},
+			build:   config.Build{RepoLink: "https://github.com/octocat/hello-world"},
+			want:    "github",
+			wantURL: "http://localhost:8080",
+		},
+		{
+			name:    "auto detects github",
+			cfg:     config.Publish{Target: "auto"},
+			build:   config.Build{RepoLink: "https://github.com/octocat/hello-world"},
+			want:    "github",
+			wantURL: DefaultGitHubAPIURL,
+		},
+		{
+			name:    "auto detects self-managed gitlab",
+			cfg:     config.Publish{Target: "auto"},
+			build:   config.Build{RepoLink: "https://gitlab.example.com/group/project"},
+			want:    "gitlab",
+			wantURL: "https://gitlab.example.com/api/v4",
+		},
+		{
+			name:    "auto detects gitea from the drone host",
+			cfg:     config.Publish{Target: "auto"},
+			build:   config.Build{RepoLink: "https://git.example.com/org/repo", SystemHost: "drone.gitea.example.com"},
+			want:    "gitea",
+			wantURL: "https://git.example.com/api/v1",
+		},
+		{
+			name:    "forgejo",
+			cfg:     config.Publish{Target: "forgejo"},
+			build:   config.Build{RepoLink: "https://codeberg.org/org/repo"},
+			want:    "gitea",
+			wantURL: "https://codeberg.org/api/v1",
+		},
+		{name: "gitlab.com by default", cfg: config.Publish{Target: "gitlab"}, want: "gitlab", wantURL: DefaultGitLabAPIURL},
+		{name: "gitea without url", cfg: config.Publish{Target: "gitea"}, wantErr: true},
+		{
+			name:    "auto with unknown host",
+			cfg:     config.Publish{Target: "auto"},
+			build:   config.Build{RepoLink: "https://git.example.com/org/repo"},
+			wantErr: true,
+		},
+		{name: "unsupported target", cfg: config.Publish{Target: "svn"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := NewPublisher(tt.cfg, tt.build, logger)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewPublisher() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var got, url string
+			switch w := w.(type) {
+			case *GitHubWriter:
+				got, url = "github", w.client.baseURL
+			case *GitLabWriter:
+				got, url = "gitlab", w.client.baseURL
+			case *GiteaWriter:
+				got, url = "gitea", w.thread.client.baseURL
+			}
+			if got != tt.want || url != tt.wantURL {
+				t.Errorf("NewPublisher() = %s at %q, want %s at %q", got, url, tt.want, tt.wantURL)
+			}
+		})
+	}
+}
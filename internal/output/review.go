@@ -0,0 +1,62 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/gate"
+)
+
+// splitFindings separates the findings on lines of the diff, which can be
+// commented inline, from the rest. lines maps each changed file to the
+// lines a review can comment on.
+func splitFindings(findings []gate.Finding, lines map[string]map[int]bool) (inline, outside []gate.Finding) {
+	for _, f := range findings {
+		f.Path = cleanPath(f.Path)
+		if f.Path != "" && f.Line > 0 && lines[f.Path][f.Line] {
+			inline = append(inline, f)
+		} else {
+			outside = append(outside, f)
+		}
+	}
+	return inline, outside
+}
+
+// cleanPath normalizes a repository path reported by the model
+func cleanPath(p string) string {
+	p = strings.TrimPrefix(path.Clean(strings.ReplaceAll(p, "\\", "/")), "/")
+	if p == "." {
+		return ""
+	}
+	return p
+}
+
+// findingComment renders a finding as an inline review comment
+func findingComment(f gate.Finding) string {
+	return fmt.Sprintf("**[%s] %s**\n\n%s", f.Severity, f.Title, f.Message)
+}
+
+// reviewBody renders the summary of a review: the verdict, the number of
+// inline comments and the findings that could not be placed on the diff
+func reviewBody(result *Result, key string, inline int, outside []gate.Finding) string {
+	verdict := result.Verdict
+	sections := []string{marker(key) + "\n**Verdict:** " + strings.ToUpper(verdict.Verdict)}
+	if verdict.Summary != "" {
+		sections = append(sections, verdict.Summary)
+	}
+	switch {
+	case inline == 0 && len(outside) == 0:
+		sections = append(sections, "No findings.")
+	case inline > 0:
+		sections = append(sections, fmt.Sprintf("%d finding(s) commented inline.", inline))
+	}
+	if len(outside) > 0 {
+		var buf bytes.Buffer
+		buf.WriteString("### Findings outside the diff\n\n")
+		writeFindingsTable(&buf, outside)
+		sections = append(sections, strings.TrimSpace(buf.String()))
+	}
+	return strings.Join(sections, "\n\n")
+}
@@ -0,0 +1,181 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/gate"
+)
+
+// Metadata of the SARIF logs written for code scanning
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName     = "drone-openai-plugin"
+	toolURI      = "https://github.com/dewan-ahmed/drone-openai-plugin"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool         `json:"tool"`
+	Results    []sarifResult     `json:"results"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine,omitempty"`
+}
+
+// encodeSARIF converts the findings of a gate verdict into a SARIF log.
+// Findings sharing a rule id are reported under one rule.
+func encodeSARIF(result *Result) ([]byte, error) {
+	if result.Verdict == nil {
+		return nil, fmt.Errorf("sarif output requires a gate verdict")
+	}
+
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           toolName,
+			InformationURI: toolURI,
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+		Properties: map[string]string{
+			"model":   result.Model,
+			"verdict": result.Verdict.Verdict,
+		},
+	}
+	rules := make(map[string]int)
+	for _, f := range result.Verdict.Findings {
+		id := ruleID(f)
+		index, ok := rules[id]
+		if !ok {
+			index = len(run.Tool.Driver.Rules)
+			rules[id] = index
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:                   id,
+				ShortDescription:     sarifMessage{Text: f.Title},
+				DefaultConfiguration: sarifConfiguration{Level: sarifLevel(f.Severity)},
+			})
+		}
+
+		message := f.Title
+		if f.Message != "" {
+			message += ": " + f.Message
+		}
+		res := sarifResult{
+			RuleID:    id,
+			RuleIndex: index,
+			Level:     sarifLevel(f.Severity),
+			Message:   sarifMessage{Text: message},
+		}
+		if path := cleanPath(f.Path); path != "" {
+			location := sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: path, URIBaseID: "%SRCROOT%"},
+			}
+			if f.Line > 0 {
+				location.Region = &sarifRegion{StartLine: f.Line}
+				if f.EndLine > f.Line {
+					location.Region.EndLine = f.EndLine
+				}
+			}
+			res.Locations = []sarifLocation{{PhysicalLocation: location}}
+		}
+		run.Results = append(run.Results, res)
+	}
+
+	data, err := json.MarshalIndent(sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// sarifLevel maps a finding severity to a SARIF result level
+func sarifLevel(severity string) string {
+	s, err := gate.ParseSeverity(severity)
+	switch {
+	case err != nil:
+		return "warning"
+	case s >= gate.SeverityHigh:
+		return "error"
+	case s == gate.SeverityMedium:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// nonSlug matches the characters replaced when deriving a rule id
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ruleID returns the rule id of a finding, derived from the title when the
+// model did not give one
+func ruleID(f gate.Finding) string {
+	id := strings.TrimSpace(f.Rule)
+	if id == "" {
+		id = strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(f.Title), "-"), "-")
+	}
+	if id == "" {
+		id = "finding"
+	}
+	return id
+}
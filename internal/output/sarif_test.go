@@ -0,0 +1,69 @@
+package output
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/gate"
+)
+
+func TestEncodeSARIF(t *testing.T) {
+	result := testResult()
+	result.Verdict = &gate.Verdict{
+		Verdict: "fail",
+		Findings: []gate.Finding{
+			{Severity: "critical", Rule: "sql-injection", Title: "SQL injection", Message: "Use parameters", Path: "./db/query.go", Line: 42, EndLine: 45},
+			{Severity: "medium", Rule: "sql-injection", Title: "SQL injection", Message: "Another query", Path: "db/other.go", Line: 7, EndLine: 7},
+			{Severity: "low", Title: "Missing README!", Message: "Add docs"},
+		},
+	}
+
+	data, err := encodeSARIF(result)
+	if err != nil {
+		t.Fatalf("encodeSARIF() error = %v", err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("SARIF output is not valid JSON: %v", err)
+	}
+
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("Expected a SARIF 2.1.0 log with one run, got %s with %d runs", log.Version, len(log.Runs))
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 2 {
+		t.Fatalf("Expected findings to share rules, got %+v", run.Tool.Driver.Rules)
+	}
+	if run.Tool.Driver.Rules[1].ID != "missing-readme" {
+		t.Errorf("Rule id should be derived from the title, got %q", run.Tool.Driver.Rules[1].ID)
+	}
+
+	if len(run.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(run.Results))
+	}
+	first := run.Results[0]
+	if first.RuleID != "sql-injection" || first.Level != "error" || first.Message.Text != "SQL injection: Use parameters" {
+		t.Errorf("Result = %+v", first)
+	}
+	location := first.Locations[0].PhysicalLocation
+	if location.ArtifactLocation.URI != "db/query.go" || location.ArtifactLocation.URIBaseID != "%SRCROOT%" {
+		t.Errorf("Artifact location = %+v", location.ArtifactLocation)
+	}
+	if location.Region == nil || location.Region.StartLine != 42 || location.Region.EndLine != 45 {
+		t.Errorf("Region = %+v, want lines 42 to 45", location.Region)
+	}
+
+	second := run.Results[1]
+	if second.RuleIndex != 0 || second.Level != "warning" || second.Locations[0].PhysicalLocation.Region.EndLine != 0 {
+		t.Errorf("Single line result = %+v", second)
+	}
+	if third := run.Results[2]; third.Level != "note" || third.RuleIndex != 1 || len(third.Locations) != 0 {
+		t.Errorf("Result without location = %+v", third)
+	}
+}
+
+func TestEncodeSARIF_RequiresVerdict(t *testing.T) {
+	if _, err := encodeSARIF(testResult()); err == nil {
+		t.Error("Expected error without a verdict, got nil")
+	}
+}
@@ -0,0 +1,81 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+const responseHeader = "\n=== OpenAI Response ==="
+
+// StdoutWriter prints the response to the build log
+type StdoutWriter struct {
+	out    io.Writer
+	logger *slog.Logger
+}
+
+// NewStdoutWriter creates a writer that prints to stdout
+func NewStdoutWriter(logger *slog.Logger) *StdoutWriter {
+	return &StdoutWriter{
+		out:    os.Stdout,
+		logger: logger,
+	}
+}
+
+// Write prints the response content followed by token usage. Streamed
+// content has already been printed by a StreamPrinter, so only the footer
+// is written.
+func (w *StdoutWriter) Write(result *Result) error {
+	w.logger.Info("token usage",
+		"prompt_tokens", result.Usage.PromptTokens,
+		"completion_tokens", result.Usage.CompletionTokens,
+		"total_tokens", result.Usage.TotalTokens,
+	)
+
+	if result.Streamed {
+		fmt.Fprintln(w.out)
+	} else {
+		fmt.Fprintln(w.out, responseHeader)
+		fmt.Fprintln(w.out, result.Content)
+	}
+	fmt.Fprintln(w.out, "=======================")
+	if result.Verdict != nil {
+		fmt.Fprintf(w.out, "Verdict: %s (%d findings)\n", result.Verdict.Verdict, len(result.Verdict.Findings))
+	}
+	fmt.Fprintf(w.out, "Tokens used: %d (prompt: %d, completion: %d)\n",
+		result.Usage.TotalTokens, result.Usage.PromptTokens, result.Usage.CompletionTokens)
+	return nil
+}
+
+// StreamPrinter prints streamed tokens to stdout as they arrive
+type StreamPrinter struct {
+	out     io.Writer
+	mu      sync.Mutex
+	started bool
+}
+
+// NewStreamPrinter creates a printer that writes to stdout
+func NewStreamPrinter() *StreamPrinter {
+	return &StreamPrinter{out: os.Stdout}
+}
+
+// Print writes a chunk of streamed content, preceded by the response header
+// on the first chunk
+func (p *StreamPrinter) Print(text string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.started {
+		fmt.Fprintln(p.out, responseHeader)
+		p.started = true
+	}
+	fmt.Fprint(p.out, text)
+}
+
+// Started reports whether any content has been printed
+func (p *StreamPrinter) Started() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.started
+}
@@ -0,0 +1,75 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/config"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/gate"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/openai"
+)
+
+// Supported output formats
+const (
+	FormatText     = "text"
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+	FormatSARIF    = "sarif"
+	FormatJUnit    = "junit"
+)
+
+// Result holds everything a Writer needs to render a response
+type Result struct {
+	Content        string
+	Model          string // model that answered
+	RequestedModel string // primary model, differs from Model after a fallback
+	FinishReason   string
+	Usage          openai.Usage
+	Duration       time.Duration
+	Build          config.Build
+	Streamed       bool          // content was already printed to stdout while streaming
+	Verdict        *gate.Verdict // structured verdict in gate mode
+	FailOn         gate.Severity // lowest severity of a blocking finding in gate mode
+}
+
+// Writer writes a chat completion result to a destination
+type Writer interface {
+	Write(result *Result) error
+}
+
+// NewWriter creates the writer for the given format and output path. The
+// response is always printed to stdout; when path is set it is also written
+// to that file encoded in the requested format.
+func NewWriter(format, path string, logger *slog.Logger) (Writer, error) {
+	if format == "" {
+		format = FormatText
+	}
+	encode, ok := encoders[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported output format %q", format)
+	}
+
+	writers := MultiWriter{NewStdoutWriter(logger)}
+	if path != "" {
+		writers = append(writers, NewFileWriter(path, encode, logger))
+	} else if format != FormatText {
+		logger.Warn("output format has no effect without an output file", "format", format)
+	}
+	return writers, nil
+}
+
+// MultiWriter writes a result to every writer in the list
+type MultiWriter []Writer
+
+// Write sends the result to each writer, returning all errors encountered
+func (m MultiWriter) Write(result *Result) error {
+	var errs []error
+	for _, w := range m {
+		if err := w.Write(result); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
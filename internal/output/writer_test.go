@@ -0,0 +1,226 @@
+package output
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/config"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/gate"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/openai"
+)
+
+func testResult() *Result {
+	return &Result{
+		Content:        "Hello from the model",
+		Model:          "gpt-4o-mini",
+		RequestedModel: "gpt-4o",
+		FinishReason:   "stop",
+		Usage: openai.Usage{
+			PromptTokens:     10,
+			CompletionTokens: 5,
+			TotalTokens:      15,
+		},
+		Duration: 1500 * time.Millisecond,
+		Build: config.Build{
+			Repo:      "octocat/hello-world",
+			CommitSHA: "abc123",
+		},
+	}
+}
+
+func TestNewWriter_UnsupportedFormat(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	_, err := NewWriter("xml", "", logger)
+	if err == nil {
+		t.Error("Expected error for unsupported format, got nil")
+	}
+}
+
+func TestNewWriter_Formats(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tests := []struct {
+		name   string
+		format string
+		check  func(t *testing.T, data []byte)
+	}{
+		{
+			name:   "default is text",
+			format: "",
+			check: func(t *testing.T, data []byte) {
+				if string(data) != "Hello from the model" {
+					t.Errorf("Content = %q, want raw response", string(data))
+				}
+			},
+		},
+		{
+			name:   "json",
+			format: FormatJSON,
+			check: func(t *testing.T, data []byte) {
+				var doc map[string]interface{}
+				if err := json.Unmarshal(data, &doc); err != nil {
+					t.Fatalf("Output is not valid JSON: %v", err)
+				}
+				if doc["content"] != "Hello from the model" {
+					t.Errorf("content = %v, want response", doc["content"])
+				}
+				if doc["model"] != "gpt-4o-mini" {
+					t.Errorf("model = %v, want gpt-4o-mini", doc["model"])
+				}
+				usage, ok := doc["usage"].(map[string]interface{})
+				if !ok {
+					t.Fatalf("usage missing from output")
+				}
+				if usage["total_tokens"] != float64(15) {
+					t.Errorf("total_tokens = %v, want 15", usage["total_tokens"])
+				}
+				if doc["requested_model"] != "gpt-4o" {
+					t.Errorf("requested_model = %v, want gpt-4o", doc["requested_model"])
+				}
+				if doc["schema_version"] != SchemaVersion {
+					t.Errorf("schema_version = %v, want %v", doc["schema_version"], SchemaVersion)
+				}
+				if doc["finish_reason"] != "stop" {
+					t.Errorf("finish_reason = %v, want stop", doc["finish_reason"])
+				}
+				if doc["duration_ms"] != float64(1500) {
+					t.Errorf("duration_ms = %v, want 1500", doc["duration_ms"])
+				}
+				build, ok := doc["build"].(map[string]interface{})
+				if !ok {
+					t.Fatalf("build missing from output")
+				}
+				if build["repo"] != "octocat/hello-world" || build["commit_sha"] != "abc123" {
+					t.Errorf("build = %v, want repo and commit from metadata", build)
+				}
+			},
+		},
+		{
+			name:   "markdown",
+			format: FormatMarkdown,
+			check: func(t *testing.T, data []byte) {
+				content := string(data)
+				if !strings.HasPrefix(content, "# OpenAI Response") {
+					t.Errorf("Markdown report should start with a heading, got %q", content)
+				}
+				if !strings.Contains(content, "Hello from the model") {
+					t.Error("Markdown report should contain the response")
+				}
+				if !strings.Contains(content, "| 10 | 5 | 15 |") {
+					t.Error("Markdown report should contain the usage table")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "nested", "result")
+			writer, err := NewWriter(tt.format, path, logger)
+			if err != nil {
+				t.Fatalf("NewWriter() error = %v", err)
+			}
+			if err := writer.Write(testResult()); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read output file: %v", err)
+			}
+			tt.check(t, data)
+		})
+	}
+}
+
+func TestNewWriter_Verdict(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	result := testResult()
+	result.Verdict = &gate.Verdict{
+		Verdict: "warn",
+		Summary: "Minor issues",
+		Findings: []gate.Finding{
+			{Severity: "low", Title: "Unused variable", Message: "x is never read", Path: "main.go", Line: 7},
+		},
+	}
+
+	dir := t.TempDir()
+	for _, format := range []string{FormatJSON, FormatMarkdown} {
+		path := filepath.Join(dir, "result."+format)
+		writer, err := NewWriter(format, path, logger)
+		if err != nil {
+			t.Fatalf("NewWriter(%s) error = %v", format, err)
+		}
+		if err := writer.Write(result); err != nil {
+			t.Fatalf("Write(%s) error = %v", format, err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "result.json"))
+	if err != nil {
+		t.Fatalf("Failed to read JSON output: %v", err)
+	}
+	var doc struct {
+		Verdict gate.Verdict `json:"verdict"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if doc.Verdict.Verdict != "warn" || len(doc.Verdict.Findings) != 1 {
+		t.Errorf("verdict = %+v, want warn with one finding", doc.Verdict)
+	}
+
+	data, err = os.ReadFile(filepath.Join(dir, "result.markdown"))
+	if err != nil {
+		t.Fatalf("Failed to read markdown output: %v", err)
+	}
+	for _, want := range []string{"**Verdict:** WARN", "| low | main.go:7 | **Unused variable** x is never read |"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Markdown report should contain %q, got %q", want, string(data))
+		}
+	}
+}
+
+func TestStdoutWriter(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	var buf strings.Builder
+	writer := &StdoutWriter{out: &buf, logger: logger}
+
+	if err := writer.Write(testResult()); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "Hello from the model") {
+		t.Errorf("Stdout output should contain the response, got %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "Tokens used: 15") {
+		t.Errorf("Stdout output should contain token usage, got %q", buf.String())
+	}
+}
+
+func TestStdoutWriter_Streamed(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	var buf strings.Builder
+	printer := &StreamPrinter{out: &buf}
+	writer := &StdoutWriter{out: &buf, logger: logger}
+
+	printer.Print("Hello ")
+	printer.Print("from the model")
+	result := testResult()
+	result.Streamed = printer.Started()
+	if err := writer.Write(result); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if strings.Count(buf.String(), "Hello from the model") != 1 {
+		t.Errorf("Streamed content should be printed exactly once, got %q", buf.String())
+	}
+	if strings.Count(buf.String(), "=== OpenAI Response ===") != 1 {
+		t.Errorf("Header should be printed exactly once, got %q", buf.String())
+	}
+}
@@ -0,0 +1,142 @@
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// extensions are the file types of prompt definitions, in lookup order
+var extensions = []string{".md", ".yaml", ".yml"}
+
+// Definition is a prompt loaded from a file. Markdown files hold the prompt
+// in their body with settings in YAML front matter, YAML files hold the
+// prompt under the prompt key. Any other file is a plain prompt.
+type Definition struct {
+	Name         string   `yaml:"name"`
+	Description  string   `yaml:"description"`
+	Model        string   `yaml:"model"`
+	Temperature  *float64 `yaml:"temperature"`
+	MaxTokens    *int     `yaml:"max_tokens"`
+	SystemPrompt string   `yaml:"system_prompt"`
+	Prompt       string   `yaml:"prompt"`
+	// Schema is an inline JSON Schema or a path to a schema file. Relative
+	// paths are resolved against the directory of the definition.
+	Schema string `yaml:"-"`
+}
+
+// rawDefinition accepts the schema as a path or an inline YAML mapping
+type rawDefinition struct {
+	Definition `yaml:",inline"`
+	Schema     yaml.Node `yaml:"schema"`
+}
+
+// Lookup loads the definition called name from the catalog directory.
+// Names may contain slashes to select definitions in subdirectories.
+func Lookup(dir, name string) (*Definition, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if name == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("invalid prompt name %q", name)
+	}
+	for _, ext := range extensions {
+		path := filepath.Join(dir, clean+ext)
+		if _, err := os.Stat(path); err == nil {
+			def, err := LoadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if def.Name == "" {
+				def.Name = name
+			}
+			return def, nil
+		}
+	}
+	return nil, fmt.Errorf("prompt %q not found in %s", name, dir)
+}
+
+// LoadFile loads a prompt definition from a file
+func LoadFile(path string) (*Definition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading prompt file: %w", err)
+	}
+
+	var def *Definition
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		def, err = parseDefinition(data)
+	case ".md":
+		def, err = parseMarkdown(string(data))
+	default:
+		def = &Definition{Prompt: string(data)}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing prompt file %s: %w", path, err)
+	}
+
+	def.Prompt = strings.TrimSpace(def.Prompt)
+	def.SystemPrompt = strings.TrimSpace(def.SystemPrompt)
+	if def.Schema != "" && !strings.HasPrefix(def.Schema, "{") && !filepath.IsAbs(def.Schema) {
+		def.Schema = filepath.Join(filepath.Dir(path), def.Schema)
+	}
+	return def, nil
+}
+
+// parseMarkdown splits optional "---" delimited front matter from the body
+func parseMarkdown(text string) (*Definition, error) {
+	text = strings.TrimPrefix(text, "\ufeff")
+	rest, ok := strings.CutPrefix(strings.ReplaceAll(text, "\r\n", "\n"), "---\n")
+	if !ok {
+		return &Definition{Prompt: text}, nil
+	}
+	front, body, found := strings.Cut(rest, "\n---\n")
+	if !found {
+		front, found = strings.CutSuffix(rest, "\n---")
+		if !found {
+			return nil, fmt.Errorf("front matter is not closed with ---")
+		}
+	}
+
+	def, err := parseDefinition([]byte(front))
+	if err != nil {
+		return nil, err
+	}
+	if def.Prompt != "" {
+		return nil, fmt.Errorf("prompt must be given in the body, not the front matter")
+	}
+	def.Prompt = body
+	return def, nil
+}
+
+// parseDefinition decodes YAML settings, converting an inline schema
+// mapping to JSON
+func parseDefinition(data []byte) (*Definition, error) {
+	var raw rawDefinition
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	def := raw.Definition
+	switch raw.Schema.Kind {
+	case 0:
+	case yaml.ScalarNode:
+		def.Schema = raw.Schema.Value
+	case yaml.MappingNode:
+		var doc map[string]any
+		if err := raw.Schema.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("invalid schema: %w", err)
+		}
+		encoded, err := json.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema: %w", err)
+		}
+		def.Schema = string(encoded)
+	default:
+		return nil, fmt.Errorf("schema must be a path or a mapping")
+	}
+	return &def, nil
+}
@@ -0,0 +1,145 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    Definition
+		wantErr bool
+	}{
+		{
+			name:    "markdown with front matter",
+			file:    "review.md",
+			content: "---\nmodel: gpt-4o\ntemperature: 0.2\nmax_tokens: 800\nsystem_prompt: You are strict.\nschema: verdict.json\n---\n\nReview this code.\n",
+			want:    Definition{Model: "gpt-4o", SystemPrompt: "You are strict.", Prompt: "Review this code.", Schema: filepath.Join(dir, "verdict.json")},
+		},
+		{
+			name:    "markdown without front matter",
+			file:    "plain.md",
+			content: "# Review\n\nReview this code.\n",
+			want:    Definition{Prompt: "# Review\n\nReview this code."},
+		},
+		{
+			name:    "markdown with empty body",
+			file:    "empty.md",
+			content: "---\nmodel: gpt-4o\n---",
+			want:    Definition{Model: "gpt-4o"},
+		},
+		{
+			name:    "yaml with inline schema",
+			file:    "summary.yaml",
+			content: "prompt: Summarize the changes\nschema:\n  type: object\n  required: [summary]\n",
+			want:    Definition{Prompt: "Summarize the changes", Schema: `{"required":["summary"],"type":"object"}`},
+		},
+		{
+			name:    "plain text",
+			file:    "prompt.txt",
+			content: "Explain this code\n",
+			want:    Definition{Prompt: "Explain this code"},
+		},
+		{
+			name:    "unclosed front matter",
+			file:    "broken.md",
+			content: "---\nmodel: gpt-4o\nReview this code.\n",
+			wantErr: true,
+		},
+		{
+			name:    "prompt in front matter",
+			file:    "duplicate.md",
+			content: "---\nprompt: one\n---\ntwo\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid yaml",
+			file:    "invalid.yml",
+			content: "prompt: [unclosed\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			writeFile(t, path, tt.content)
+
+			def, err := LoadFile(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if def.Model != tt.want.Model || def.SystemPrompt != tt.want.SystemPrompt ||
+				def.Prompt != tt.want.Prompt || def.Schema != tt.want.Schema {
+				t.Errorf("LoadFile() = %+v, want %+v", *def, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFile_Settings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "review.md")
+	writeFile(t, path, "---\ntemperature: 0\nmax_tokens: 800\n---\nReview\n")
+
+	def, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if def.Temperature == nil || *def.Temperature != 0 {
+		t.Errorf("Temperature = %v, want an explicit 0", def.Temperature)
+	}
+	if def.MaxTokens == nil || *def.MaxTokens != 800 {
+		t.Errorf("MaxTokens = %v, want 800", def.MaxTokens)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "security.md"), "Find vulnerabilities\n")
+	writeFile(t, filepath.Join(dir, "review", "go.yaml"), "name: Go review\nprompt: Review Go code\n")
+
+	tests := []struct {
+		name     string
+		lookup   string
+		wantName string
+		wantErr  bool
+	}{
+		{"markdown", "security", "security", false},
+		{"subdirectory yaml", "review/go", "Go review", false},
+		{"not found", "missing", "", true},
+		{"empty name", "", "", true},
+		{"parent directory", "../security", "", true},
+		{"absolute path", filepath.Join(dir, "security"), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def, err := Lookup(dir, tt.lookup)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Lookup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && def.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", def.Name, tt.wantName)
+			}
+		})
+	}
+}
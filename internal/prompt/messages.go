@@ -0,0 +1,60 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/openai"
+)
+
+// Turn is a message of a conversation given in the messages setting
+type Turn struct {
+	Role    string `yaml:"role"`
+	Content string `yaml:"content"`
+}
+
+// LoadMessages parses a list of conversation turns given inline as a JSON
+// or YAML list, or as a path to a file holding one. Drone passes list
+// settings as JSON, which YAML accepts as well.
+func LoadMessages(value string) ([]Turn, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	data := []byte(value)
+	if !strings.HasPrefix(value, "[") && !strings.HasPrefix(value, "-") {
+		var err error
+		if data, err = os.ReadFile(value); err != nil {
+			return nil, fmt.Errorf("error reading messages file: %w", err)
+		}
+	}
+
+	var turns []Turn
+	if err := yaml.Unmarshal(data, &turns); err != nil {
+		return nil, fmt.Errorf("error parsing messages: %w", err)
+	}
+	for i, turn := range turns {
+		switch turn.Role {
+		case "system", "user", "assistant":
+		default:
+			return nil, fmt.Errorf("message %d: unsupported role %q", i+1, turn.Role)
+		}
+		if strings.TrimSpace(turn.Content) == "" {
+			return nil, fmt.Errorf("message %d: content is required", i+1)
+		}
+	}
+	return turns, nil
+}
+
+// Messages converts the turns to provider messages
+func Messages(turns []Turn) []openai.Message {
+	messages := make([]openai.Message, len(turns))
+	for i, turn := range turns {
+		messages[i] = openai.Message{Role: turn.Role, Content: turn.Content}
+	}
+	return messages
+}
@@ -0,0 +1,67 @@
+package prompt
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadMessages(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "examples.yaml")
+	writeFile(t, path, "- role: user\n  content: |\n    func add(a, b int) int { return a - b }\n- role: assistant\n  content: \"add: subtracts instead of adding\"\n")
+
+	tests := []struct {
+		name    string
+		value   string
+		want    []Turn
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{
+			"json list",
+			`[{"role": "user", "content": "Hi"}, {"role": "assistant", "content": "Hello"}]`,
+			[]Turn{{"user", "Hi"}, {"assistant", "Hello"}},
+			false,
+		},
+		{
+			"yaml list",
+			"- role: user\n  content: Hi\n",
+			[]Turn{{"user", "Hi"}},
+			false,
+		},
+		{
+			"file",
+			path,
+			[]Turn{{"user", "func add(a, b int) int { return a - b }\n"}, {"assistant", "add: subtracts instead of adding"}},
+			false,
+		},
+		{"missing file", filepath.Join(dir, "missing.yaml"), nil, true},
+		{"unsupported role", `[{"role": "tool", "content": "Hi"}]`, nil, true},
+		{"missing content", `[{"role": "user"}]`, nil, true},
+		{"not a list", "[unclosed", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadMessages(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadMessages() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("LoadMessages() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Turn %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMessages(t *testing.T) {
+	messages := Messages([]Turn{{"user", "Hi"}, {"assistant", "Hello"}})
+	if len(messages) != 2 || messages[1].Role != "assistant" || messages[1].Content != "Hello" {
+		t.Errorf("Messages() = %+v", messages)
+	}
+}
@@ -0,0 +1,136 @@
+package prompt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/agent"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/config"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/file"
+)
+
+// Data is the context available to prompt templates
+type Data struct {
+	Repo             string
+	Branch           string
+	Commit           string
+	CommitMessage    string
+	Author           string
+	Event            string
+	BuildNumber      string
+	PullRequest      string
+	PullRequestTitle string
+	TargetBranch     string
+	// Files maps the path of every attached text file to its content
+	Files map[string]string
+
+	workspace string   // directory readFile is confined to
+	env       []string // variables env may read besides the build variables
+}
+
+// NewData builds the template context from the build metadata and the
+// attached files. env lists the environment variables templates may read
+// in addition to the DRONE_* and CI_* build variables.
+func NewData(build config.Build, attachments []file.Attachment, env []string) Data {
+	files := make(map[string]string, len(attachments))
+	for _, a := range attachments {
+		if !a.IsImage() {
+			files[a.Path] = string(a.Data)
+		}
+	}
+	return Data{
+		Repo:             build.Repo,
+		Branch:           build.Branch,
+		Commit:           build.CommitSHA,
+		CommitMessage:    build.CommitMessage,
+		Author:           build.Author,
+		Event:            build.Event,
+		BuildNumber:      build.Number,
+		PullRequest:      build.PullRequest,
+		PullRequestTitle: build.PullRequestTitle,
+		TargetBranch:     build.TargetBranch,
+		Files:            files,
+		workspace:        workspace(build),
+		env:              env,
+	}
+}
+
+// workspace returns the directory of the repository
+func workspace(build config.Build) string {
+	if build.Workspace == "" {
+		return "."
+	}
+	return build.Workspace
+}
+
+// funcs returns the helper functions available to prompt templates
+func (d Data) funcs() template.FuncMap {
+	return template.FuncMap{
+		"readFile": d.readFile,
+		"truncate": truncate,
+		"env":      d.getenv,
+	}
+}
+
+// Render executes text as a Go template with the given data. Text without
+// template actions is returned unchanged.
+func Render(name, text string, data Data) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(data.funcs()).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("error parsing %s template: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error rendering %s template: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// readFile returns the content of a file in the workspace. Prompts may come
+// from the repository, so files outside it are refused like in agent mode.
+func (d Data) readFile(path string) (string, error) {
+	ws, err := agent.NewWorkspace(d.workspace, nil)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := ws.Resolve(path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// getenv returns an environment variable. Plugin settings and the clone
+// credentials hold secrets, so only the build variables and the variables
+// listed in template_env are available.
+func (d Data) getenv(name string) (string, error) {
+	build := (strings.HasPrefix(name, "DRONE_") || strings.HasPrefix(name, "CI_")) && !strings.HasPrefix(name, "DRONE_NETRC_")
+	if !build && !slices.Contains(d.env, name) {
+		return "", fmt.Errorf("environment variable %q is not available to templates, add it to template_env", name)
+	}
+	return os.Getenv(name), nil
+}
+
+// truncate shortens s to at most n characters. The argument order allows
+// piping, as in {{ readFile "CHANGELOG.md" | truncate 2000 }}.
+func truncate(n int, s string) string {
+	if n < 0 {
+		n = 0
+	}
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
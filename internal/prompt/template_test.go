@@ -0,0 +1,92 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/config"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/file"
+)
+
+func TestRender(t *testing.T) {
+	dir := t.TempDir()
+	notes := filepath.Join(dir, "notes.md")
+	if err := os.WriteFile(notes, []byte("release notes"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("secret"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	t.Setenv("REVIEW_FOCUS", "security")
+	t.Setenv("DRONE_STAGE_NAME", "review")
+	t.Setenv("DRONE_NETRC_PASSWORD", "clone-token")
+	t.Setenv("PLUGIN_API_KEY", "sk-test")
+
+	data := NewData(config.Build{
+		Repo:        "octocat/hello-world",
+		Branch:      "feature",
+		CommitSHA:   "abc123",
+		Author:      "octocat",
+		PullRequest: "42",
+		Workspace:   dir,
+	}, []file.Attachment{
+		{Path: "main.go", Data: []byte("package main")},
+		{Path: "logo.png", Data: []byte("png"), MimeType: "image/png"},
+	}, []string{"REVIEW_FOCUS"})
+
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{"plain text", "Review this code", "Review this code", false},
+		{"build variables", "Review PR #{{ .PullRequest }} by {{ .Author }} on {{ .Repo }}@{{ .Branch }}", "Review PR #42 by octocat on octocat/hello-world@feature", false},
+		{"conditional", "{{ if .PullRequest }}pull request{{ else }}push{{ end }}", "pull request", false},
+		{"file contents", `{{ index .Files "main.go" }}`, "package main", false},
+		{"images are not files", `{{ len .Files }}`, "1", false},
+		{"readFile and truncate", `{{ readFile "notes.md" | truncate 7 }}`, "release", false},
+		{"readFile absolute path in workspace", `{{ readFile "` + notes + `" }}`, "release notes", false},
+		{"readFile outside workspace", `{{ readFile "` + outside + `" }}`, "", true},
+		{"readFile parent directory", `{{ readFile "../secret.txt" }}`, "", true},
+		{"env listed", `Focus on {{ env "REVIEW_FOCUS" }}`, "Focus on security", false},
+		{"env build variable", `{{ env "DRONE_STAGE_NAME" }}`, "review", false},
+		{"env plugin setting", `{{ env "PLUGIN_API_KEY" }}`, "", true},
+		{"env clone credentials", `{{ env "DRONE_NETRC_PASSWORD" }}`, "", true},
+		{"unknown field", "{{ .Unknown }}", "", true},
+		{"missing file", `{{ readFile "missing.md" }}`, "", true},
+		{"syntax error", "{{ .Repo ", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render("prompt", tt.text, data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		n    int
+		s    string
+		want string
+	}{
+		{3, "abcdef", "abc"},
+		{10, "abc", "abc"},
+		{2, "héllo", "hé"},
+		{-1, "abc", ""},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.n, tt.s); got != tt.want {
+			t.Errorf("truncate(%d, %q) = %q, want %q", tt.n, tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// schemaURL is the location the schema is registered under when compiling
+const schemaURL = "response.schema.json"
+
+// Schema is a JSON Schema used to constrain and validate model output
+type Schema struct {
+	Name     string
+	Document map[string]any
+	compiled *jsonschema.Schema
+}
+
+// Load reads a schema given either inline as a JSON object or as a path to
+// a schema file
+func Load(value string) (*Schema, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "{") {
+		return Parse("", []byte(value))
+	}
+
+	data, err := os.ReadFile(value)
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema file: %w", err)
+	}
+	name := strings.TrimSuffix(filepath.Base(value), filepath.Ext(value))
+	name = strings.TrimSuffix(name, ".schema")
+	return Parse(name, data)
+}
+
+// Parse compiles a schema document. The name defaults to the schema title.
+func Parse(name string, data []byte) (*Schema, error) {
+	var document map[string]any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("schema is not a JSON object: %w", err)
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing schema: %w", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(schemaURL, doc); err != nil {
+		return nil, fmt.Errorf("error loading schema: %w", err)
+	}
+	compiled, err := compiler.Compile(schemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	if title, ok := document["title"].(string); ok && title != "" {
+		name = title
+	}
+	return &Schema{
+		Name:     sanitizeName(name),
+		Document: document,
+		compiled: compiled,
+	}, nil
+}
+
+// Validate checks that content is a JSON document matching the schema. A
+// surrounding markdown code fence is removed first; the returned string is
+// the bare JSON document.
+func (s *Schema) Validate(content string) (string, error) {
+	content = trimCodeFence(content)
+
+	value, err := jsonschema.UnmarshalJSON(strings.NewReader(content))
+	if err != nil {
+		return "", fmt.Errorf("response is not valid JSON: %w", err)
+	}
+	if err := s.compiled.Validate(value); err != nil {
+		return "", fmt.Errorf("response does not match schema: %w", err)
+	}
+	return content, nil
+}
+
+// trimCodeFence removes a ```json ... ``` fence around the content
+func trimCodeFence(content string) string {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "```") {
+		return content
+	}
+	content = strings.TrimPrefix(content, "```")
+	if newline := strings.IndexByte(content, '\n'); newline >= 0 {
+		content = content[newline+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(content), "```"))
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// sanitizeName turns a title into a name accepted by the OpenAI API
+func sanitizeName(name string) string {
+	name = strings.Trim(invalidNameChars.ReplaceAllString(name, "_"), "_")
+	if name == "" {
+		return "response"
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
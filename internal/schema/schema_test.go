@@ -0,0 +1,92 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const verdictSchema = `{
+	"title": "Review Verdict",
+	"type": "object",
+	"properties": {
+		"verdict": {"type": "string", "enum": ["pass", "fail"]},
+		"issues": {"type": "array", "items": {"type": "string"}}
+	},
+	"required": ["verdict", "issues"],
+	"additionalProperties": false
+}`
+
+func TestLoad(t *testing.T) {
+	t.Run("inline", func(t *testing.T) {
+		s, err := Load(verdictSchema)
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if s.Name != "Review_Verdict" {
+			t.Errorf("Name = %q, want Review_Verdict", s.Name)
+		}
+		if s.Document["type"] != "object" {
+			t.Errorf("Document type = %v, want object", s.Document["type"])
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "verdict.schema.json")
+		if err := os.WriteFile(path, []byte(`{"type": "object"}`), 0644); err != nil {
+			t.Fatalf("Failed to write schema: %v", err)
+		}
+		s, err := Load(path)
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if s.Name != "verdict" {
+			t.Errorf("Name = %q, want verdict", s.Name)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := Load("/nonexistent/schema.json"); err == nil {
+			t.Error("Expected error for missing schema file, got nil")
+		}
+	})
+
+	t.Run("invalid schema", func(t *testing.T) {
+		if _, err := Load(`{"type": 42}`); err == nil {
+			t.Error("Expected error for invalid schema, got nil")
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	s, err := Load(verdictSchema)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{"valid", `{"verdict": "pass", "issues": []}`, `{"verdict": "pass", "issues": []}`, false},
+		{"code fence", "```json\n{\"verdict\": \"fail\", \"issues\": [\"x\"]}\n```", `{"verdict": "fail", "issues": ["x"]}`, false},
+		{"not json", "Looks good to me", "", true},
+		{"wrong enum", `{"verdict": "maybe", "issues": []}`, "", true},
+		{"missing field", `{"verdict": "pass"}`, "", true},
+		{"extra field", `{"verdict": "pass", "issues": [], "score": 1}`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Validate(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
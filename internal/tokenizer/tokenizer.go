@@ -0,0 +1,228 @@
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dlclark/regexp2"
+)
+
+// Encoding names of the supported tiktoken rank files
+const (
+	CL100K = "cl100k_base"
+	O200K  = "o200k_base"
+)
+
+// patterns split text into the pieces that are merged by byte pair encoding.
+// They match the pre-tokenizers used by tiktoken.
+var patterns = map[string]string{
+	CL100K: `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`,
+	O200K: strings.Join([]string{
+		`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?`,
+		`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?`,
+		`\p{N}{1,3}`,
+		` ?[^\s\p{L}\p{N}]+[\r\n/]*`,
+		`\s*[\r\n]+`,
+		`\s+(?!\S)`,
+		`\s+`,
+	}, "|"),
+}
+
+// Counter counts the tokens in a piece of text
+type Counter interface {
+	Count(text string) int
+}
+
+// Encoding is a byte pair encoding loaded from a tiktoken rank file
+type Encoding struct {
+	name    string
+	ranks   map[string]int
+	pattern *regexp2.Regexp
+}
+
+// Load reads the rank file <name>.tiktoken from dir. Each line of the file
+// holds a base64 encoded token and its rank.
+func Load(dir, name string) (*Encoding, error) {
+	expr, ok := patterns[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported encoding %q", name)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, name+".tiktoken"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading rank file: %w", err)
+	}
+	ranks, err := parseRanks(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s rank file: %w", name, err)
+	}
+
+	return &Encoding{
+		name:    name,
+		ranks:   ranks,
+		pattern: regexp2.MustCompile(expr, regexp2.None),
+	}, nil
+}
+
+// parseRanks decodes the lines of a tiktoken rank file
+func parseRanks(data []byte) (map[string]int, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		token, rank, found := strings.Cut(line, " ")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected token and rank", n)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		value, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		ranks[string(decoded)] = value
+	}
+	return ranks, scanner.Err()
+}
+
+// Name returns the encoding name
+func (e *Encoding) Name() string {
+	return e.name
+}
+
+// Encode converts text into token ranks. Special tokens are not recognised
+// and are encoded as ordinary text.
+func (e *Encoding) Encode(text string) []int {
+	var tokens []int
+	match, _ := e.pattern.FindStringMatch(text)
+	for match != nil {
+		piece := match.String()
+		if rank, ok := e.ranks[piece]; ok {
+			tokens = append(tokens, rank)
+		} else {
+			tokens = append(tokens, e.merge([]byte(piece))...)
+		}
+		match, _ = e.pattern.FindNextMatch(match)
+	}
+	return tokens
+}
+
+// Count returns the number of tokens in text
+func (e *Encoding) Count(text string) int {
+	return len(e.Encode(text))
+}
+
+// merge applies byte pair merges to a piece, repeatedly joining the
+// adjacent pair with the lowest rank until no pair is in the vocabulary
+func (e *Encoding) merge(piece []byte) []int {
+	// parts holds the start offset of every current token in piece
+	parts := make([]int, len(piece)+1)
+	for i := range parts {
+		parts[i] = i
+	}
+
+	for len(parts) > 2 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i+2 < len(parts); i++ {
+			if rank, ok := e.ranks[string(piece[parts[i]:parts[i+2]])]; ok && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+
+	tokens := make([]int, 0, len(parts)-1)
+	for i := 0; i+1 < len(parts); i++ {
+		tokens = append(tokens, e.ranks[string(piece[parts[i]:parts[i+1]])])
+	}
+	return tokens
+}
+
+// Estimator approximates token counts when no rank file is available.
+// English text and source code average about four bytes per token.
+type Estimator struct{}
+
+// Count returns the estimated number of tokens in text
+func (Estimator) Count(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// EncodingForModel returns the tiktoken encoding used by an OpenAI model.
+// Other providers' models are counted with o200k_base as an approximation.
+func EncodingForModel(model string) string {
+	model = strings.ToLower(model)
+	for _, prefix := range []string{"gpt-4-", "gpt-3.5", "text-embedding-3", "text-embedding-ada"} {
+		if strings.HasPrefix(model, prefix) {
+			return CL100K
+		}
+	}
+	if model == "gpt-4" {
+		return CL100K
+	}
+	return O200K
+}
+
+// ForModel returns a counter for the model, loading its rank file from dir.
+// It falls back to an Estimator when dir is empty or the file is missing.
+func ForModel(model, dir string, logger *slog.Logger) Counter {
+	name := EncodingForModel(model)
+	if dir == "" {
+		logger.Info("no tokenizer directory configured, estimating token counts", "model", model)
+		return Estimator{}
+	}
+	enc, err := Load(dir, name)
+	if err != nil {
+		logger.Warn("tokenizer unavailable, estimating token counts", "encoding", name, "error", err)
+		return Estimator{}
+	}
+	logger.Info("tokenizer loaded", "model", model, "encoding", name)
+	return enc
+}
+
+// contextWindows lists the context window of known model families, matched
+// by prefix. More specific prefixes must come first.
+var contextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"gpt-4.1", 1047576},
+	{"gpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo", 16385},
+	{"gpt-5", 400000},
+	{"o1", 200000},
+	{"o3", 200000},
+	{"o4", 200000},
+	{"claude", 200000},
+	{"gemini", 1048576},
+}
+
+// ContextWindow returns the context window of a model in tokens, or 0 when
+// the model is unknown
+func ContextWindow(model string) int {
+	model = strings.ToLower(model)
+	for _, w := range contextWindows {
+		if strings.HasPrefix(model, w.prefix) {
+			return w.tokens
+		}
+	}
+	return 0
+}
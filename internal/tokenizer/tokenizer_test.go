@@ -0,0 +1,147 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeRanks writes a small rank file holding every single byte followed by
+// the given merges, in the format of the tiktoken rank files
+func writeRanks(t *testing.T, dir, name string, merges ...string) {
+	t.Helper()
+	var b strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	for i, merge := range merges {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(merge)), 256+i)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".tiktoken"), []byte(b.String()), 0644); err != nil {
+		t.Fatalf("Failed to write rank file: %v", err)
+	}
+}
+
+func TestEncoding_Encode(t *testing.T) {
+	dir := t.TempDir()
+	writeRanks(t, dir, CL100K, "ab", "abc", " a")
+
+	enc, err := Load(dir, CL100K)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want []int
+	}{
+		// "abc" is a whole token, " abd" merges "ab" first as it has the lowest rank
+		{"merges by rank", "abc abd", []int{257, ' ', 256, 'd'}},
+		{"digits split in groups of three", "12345", []int{'1', '2', '3', '4', '5'}},
+		{"empty", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := enc.Encode(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Encode(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+			if enc.Count(tt.text) != len(tt.want) {
+				t.Errorf("Count(%q) = %d, want %d", tt.text, enc.Count(tt.text), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestEncoding_O200KPattern(t *testing.T) {
+	dir := t.TempDir()
+	writeRanks(t, dir, O200K, "He", "llo", "Hello", " wo", "rld", " world")
+
+	enc, err := Load(dir, O200K)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := enc.Count("Hello world"); got != 2 {
+		t.Errorf("Count() = %d, want 2", got)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Load(dir, "p50k_base"); err == nil {
+		t.Error("Expected error for unsupported encoding, got nil")
+	}
+	if _, err := Load(dir, CL100K); err == nil {
+		t.Error("Expected error for missing rank file, got nil")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, CL100K+".tiktoken"), []byte("not-base64! 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write rank file: %v", err)
+	}
+	if _, err := Load(dir, CL100K); err == nil {
+		t.Error("Expected error for malformed rank file, got nil")
+	}
+}
+
+func TestForModel(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dir := t.TempDir()
+	writeRanks(t, dir, O200K)
+
+	if _, ok := ForModel("gpt-4o", dir, logger).(*Encoding); !ok {
+		t.Error("Expected the o200k encoding for gpt-4o")
+	}
+	if _, ok := ForModel("gpt-4", dir, logger).(Estimator); !ok {
+		t.Error("Expected the estimator when the cl100k rank file is missing")
+	}
+	if _, ok := ForModel("gpt-4o", "", logger).(Estimator); !ok {
+		t.Error("Expected the estimator without a tokenizer directory")
+	}
+}
+
+func TestEstimator(t *testing.T) {
+	if got := (Estimator{}).Count("abcdefghi"); got != 3 {
+		t.Errorf("Count() = %d, want 3", got)
+	}
+}
+
+func TestEncodingForModel(t *testing.T) {
+	tests := map[string]string{
+		"gpt-4o-mini":            O200K,
+		"gpt-4.1":                O200K,
+		"o3-mini":                O200K,
+		"gpt-4":                  CL100K,
+		"gpt-4-turbo":            CL100K,
+		"gpt-3.5-turbo":          CL100K,
+		"claude-sonnet-4-5":      O200K,
+		"text-embedding-3-small": CL100K,
+	}
+	for model, want := range tests {
+		if got := EncodingForModel(model); got != want {
+			t.Errorf("EncodingForModel(%q) = %q, want %q", model, got, want)
+		}
+	}
+}
+
+func TestContextWindow(t *testing.T) {
+	tests := map[string]int{
+		"gpt-4o-mini":      128000,
+		"gpt-4.1-mini":     1047576,
+		"gpt-4":            8192,
+		"gpt-4-turbo":      128000,
+		"claude-3-5-haiku": 200000,
+		"llama3.2":         0,
+	}
+	for model, want := range tests {
+		if got := ContextWindow(model); got != want {
+			t.Errorf("ContextWindow(%q) = %d, want %d", model, got, want)
+		}
+	}
+}
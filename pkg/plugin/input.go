@@ -0,0 +1,148 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/config"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/diff"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/file"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/openai"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/tokenizer"
+)
+
+// errNoChanges is returned in diff mode when there is nothing to review
+var errNoChanges = errors.New("no changes to review")
+
+// input is the content attached to the prompt. render builds the user
+// message once the attachments have been fitted into the token budget or
+// split into chunks.
+type input struct {
+	attachments []file.Attachment
+	render      func(prompt string, attachments []file.Attachment) openai.Message
+}
+
+// loadInput reads the diff or files selected by the configuration
+func loadInput(ctx context.Context, cfg *config.Config, processor *file.Processor, logger *slog.Logger) (*input, error) {
+	switch {
+	case cfg.Source == "diff":
+		return loadDiff(ctx, cfg, logger)
+
+	case len(cfg.Files) > 0:
+		patterns := cfg.Files
+		if cfg.FilePath != "" {
+			patterns = append([]string{cfg.FilePath}, patterns...)
+		}
+		attachments, err := processor.Collect(patterns)
+		if err != nil {
+			return nil, fmt.Errorf("error processing files: %w", err)
+		}
+		return &input{
+			attachments: attachments,
+			render: func(prompt string, attachments []file.Attachment) openai.Message {
+				return file.BuildMessage(prompt, attachments)
+			},
+		}, nil
+
+	case cfg.FilePath != "":
+		logger.Info("processing file", "path", cfg.FilePath)
+		attachment, err := processor.Read(cfg.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("error processing file: %w", err)
+		}
+		return &input{
+			attachments: []file.Attachment{attachment},
+			render: func(prompt string, attachments []file.Attachment) openai.Message {
+				if len(attachments) == 0 {
+					return openai.Message{Role: "user", Content: prompt}
+				}
+				return processor.FileMessage(prompt, attachments[0])
+			},
+		}, nil
+
+	default:
+		return &input{
+			render: func(prompt string, _ []file.Attachment) openai.Message {
+				return openai.Message{Role: "user", Content: prompt}
+			},
+		}, nil
+	}
+}
+
+// loadDiff computes the diff of the build and splits it into one attachment
+// per changed file, so the budget can drop or truncate individual files
+func loadDiff(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*input, error) {
+	paths := cfg.Files
+	if cfg.FilePath != "" {
+		paths = append([]string{cfg.FilePath}, paths...)
+	}
+	// Drone also sets the target branch on pushes, where it is the pushed
+	// branch itself, so it is only the base of pull requests
+	var targetBranch string
+	if cfg.Build.Event == "pull_request" || cfg.Build.PullRequest != "" {
+		targetBranch = cfg.Build.TargetBranch
+	}
+	changes, err := diff.Compute(ctx, diff.Options{
+		Dir:          cfg.Build.Workspace,
+		Before:       cfg.Build.CommitBefore,
+		After:        cfg.Build.CommitAfter,
+		TargetBranch: targetBranch,
+		Context:      cfg.DiffContext,
+		Paths:        paths,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error computing diff: %w", err)
+	}
+	if changes.Empty() {
+		logger.Warn("no changes to review", "range", changes.Range())
+		return nil, errNoChanges
+	}
+
+	files := changes.Files()
+	attachments := make([]file.Attachment, len(files))
+	headers := make(map[string]string, len(files))
+	for i, f := range files {
+		attachments[i] = file.Attachment{Path: f.Path, Data: []byte(f.Patch)}
+		headers[f.Path] = f.Header()
+	}
+	return &input{
+		attachments: attachments,
+		render: func(prompt string, attachments []file.Attachment) openai.Message {
+			var patch strings.Builder
+			for _, a := range attachments {
+				// Map-reduce splits large patches between hunks, so later
+				// parts need the file header again
+				if !bytes.HasPrefix(a.Data, []byte("diff --git ")) {
+					patch.WriteString(headers[a.Path])
+				}
+				patch.Write(a.Data)
+			}
+			fitted := &diff.Diff{Base: changes.Base, Head: changes.Head, Patch: patch.String()}
+			return openai.Message{Role: "user", Content: diff.Prompt(prompt, fitted)}
+		},
+	}, nil
+}
+
+// inputBudget returns the number of input tokens allowed for the request.
+// Without max_input_tokens it is the smallest known context window of the
+// models that may answer, less the tokens reserved for the response. It
+// returns 0, disabling the budget, when no model has a known window.
+func inputBudget(cfg *config.Config) int {
+	if cfg.MaxInputTokens > 0 {
+		return cfg.MaxInputTokens
+	}
+	window := 0
+	for _, model := range append([]string{cfg.Model}, cfg.FallbackModels...) {
+		if w := tokenizer.ContextWindow(model); w > 0 && (window == 0 || w < window) {
+			window = w
+		}
+	}
+	if window == 0 || window <= cfg.MaxTokens {
+		return 0
+	}
+	return window - cfg.MaxTokens
+}
@@ -2,15 +2,23 @@ package plugin
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"time"
 
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/agent"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/budget"
 	"github.com/dewan-ahmed/drone-openai-plugin/internal/config"
 	"github.com/dewan-ahmed/drone-openai-plugin/internal/file"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/gate"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/mapreduce"
 	"github.com/dewan-ahmed/drone-openai-plugin/internal/openai"
 	"github.com/dewan-ahmed/drone-openai-plugin/internal/output"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/prompt"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/schema"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/tokenizer"
 )
 
 // Run executes the plugin workflow
@@ -24,7 +32,7 @@ func Run() error {
 		"version", "0.1.2",
 		"go_arch", os.Getenv("GOOS")+"/"+os.Getenv("GOARCH"),
 	)
-	
+
 	// Log environment for debugging
 	logger.Info("runtime environment",
 		"workspace", os.Getenv("DRONE_WORKSPACE"),
@@ -35,12 +43,21 @@ func Run() error {
 	// Load configuration from environment
 	cfg := config.Load()
 	logger.Info("configuration loaded",
+		"provider", cfg.Provider,
 		"model", cfg.Model,
+		"fallback_models", cfg.FallbackModels,
 		"temperature", cfg.Temperature,
 		"max_tokens", cfg.MaxTokens,
 		"timeout", cfg.Timeout,
 		"has_file", cfg.FilePath != "",
+		"prompt_file", cfg.PromptFile,
+		"prompt_name", cfg.PromptName,
+		"files", cfg.Files,
+		"source", cfg.Source,
+		"agent", cfg.Agent,
 		"has_output_file", cfg.OutputFile != "",
+		"output_format", cfg.OutputFormat,
+		"publish", cfg.Publish.Target,
 	)
 
 	// Validate configuration
@@ -49,57 +66,277 @@ func Run() error {
 		return fmt.Errorf("configuration error: %w", err)
 	}
 
+	provider, err := newProvider(cfg, logger)
+	if err != nil {
+		logger.Error("provider configuration failed", "error", err)
+		return fmt.Errorf("configuration error: %w", err)
+	}
+	provider = openai.NewRetryProvider(provider, openai.RetryPolicy{
+		MaxRetries: cfg.Retry.MaxRetries,
+		BaseDelay:  cfg.Retry.Backoff,
+		MaxDelay:   cfg.Retry.MaxBackoff,
+	}, logger)
+	if len(cfg.FallbackModels) > 0 {
+		provider = openai.NewFallbackProvider(provider, cfg.FallbackModels, logger)
+	}
+
+	return execute(cfg, provider, logger)
+}
+
+// execute builds the prompt, calls the provider and writes the response
+func execute(cfg *config.Config, provider openai.Provider, logger *slog.Logger) error {
+	if err := loadPrompts(cfg, logger); err != nil {
+		logger.Error("prompt loading failed", "error", err)
+		return fmt.Errorf("configuration error: %w", err)
+	}
+
 	// Create component instances
 	fileProcessor := file.NewProcessor(logger)
-	openaiClient := openai.NewClient(cfg.APIKey, logger)
-	outputWriter := output.NewWriter(logger)
+	outputWriter, err := output.NewWriter(cfg.OutputFormat, cfg.OutputFile, logger)
+	if err != nil {
+		logger.Error("output configuration failed", "error", err)
+		return fmt.Errorf("configuration error: %w", err)
+	}
+	publisher, err := output.NewPublisher(cfg.Publish, cfg.Build, logger)
+	if err != nil {
+		logger.Error("publish configuration failed", "error", err)
+		return fmt.Errorf("configuration error: %w", err)
+	}
+	if publisher != nil {
+		outputWriter = output.MultiWriter{outputWriter, publisher}
+	}
 
-	// Build messages for OpenAI
-	messages := []openai.Message{
-		{
-			Role:    "system",
-			Content: cfg.SystemPrompt,
-		},
+	policy, err := budget.ParsePolicy(cfg.BudgetPolicy)
+	if err != nil {
+		logger.Error("invalid budget policy", "error", err)
+		return fmt.Errorf("configuration error: %w", err)
+	}
+
+	// In agent mode the model reads what it needs from the workspace
+	var toolAgent *agent.Agent
+	if cfg.Agent {
+		dir := cfg.Build.Workspace
+		if dir == "" {
+			dir = "."
+		}
+		workspace, err := agent.NewWorkspace(dir, logger)
+		if err != nil {
+			logger.Error("workspace configuration failed", "error", err)
+			return fmt.Errorf("configuration error: %w", err)
+		}
+		toolAgent = agent.New(provider, workspace, cfg.MaxIterations, logger)
 	}
 
-	// Process user message with optional file
-	if cfg.FilePath != "" {
-		userMessage, err := fileProcessor.ProcessFileContent(cfg.Prompt, cfg.FilePath)
+	var failOn gate.Severity
+	var responseSchema *schema.Schema
+	if cfg.Gate {
+		failOn, err = gate.ParseSeverity(cfg.FailOn)
 		if err != nil {
-			logger.Error("file processing failed", "error", err)
-			return fmt.Errorf("error processing file: %w", err)
+			logger.Error("invalid fail_on severity", "error", err)
+			return fmt.Errorf("configuration error: %w", err)
 		}
-		messages = append(messages, userMessage)
-	} else {
-		messages = append(messages, openai.Message{
-			Role:    "user",
-			Content: cfg.Prompt,
-		})
+		responseSchema = gate.Schema()
+	} else if cfg.ResponseSchema != "" {
+		responseSchema, err = schema.Load(cfg.ResponseSchema)
+		if err != nil {
+			logger.Error("response schema loading failed", "error", err)
+			return fmt.Errorf("configuration error: %w", err)
+		}
+	}
+
+	// Few-shot examples and prior turns go between the system prompt and
+	// the final user message
+	turns, err := prompt.LoadMessages(cfg.Messages)
+	if err != nil {
+		logger.Error("messages loading failed", "error", err)
+		return fmt.Errorf("configuration error: %w", err)
 	}
 
 	// Create context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Timeout)*time.Second)
 	defer cancel()
 
-	// Call OpenAI API
-	logger.Info("calling openai api")
-	response, err := openaiClient.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model:       cfg.Model,
+	// Process user message with the diff or optional files
+	in, err := loadInput(ctx, cfg, fileProcessor, logger)
+	if errors.Is(err, errNoChanges) {
+		fmt.Println("\n✓ No changes to review")
+		return nil
+	}
+	if err != nil {
+		logger.Error("input processing failed", "error", err)
+		return err
+	}
+
+	// Render prompt templates with the build variables and attached files
+	userPrompt, systemPrompt := cfg.Prompt, cfg.SystemPrompt
+	if cfg.Template {
+		data := prompt.NewData(cfg.Build, in.attachments, cfg.TemplateEnv)
+		render := func(name, text string) (string, error) {
+			rendered, err := prompt.Render(name, text, data)
+			// Prompts written before templating may quote Helm or Go
+			// template syntax such as {{ .Values.image }}, which fails to
+			// parse or to execute, so they are sent verbatim unless
+			// templating was requested
+			if err != nil && !config.IsSet("TEMPLATE") {
+				logger.Warn("prompt could not be rendered as a template, sending it verbatim", "template", name, "error", err)
+				return text, nil
+			}
+			return rendered, err
+		}
+		if userPrompt, err = render("prompt", userPrompt); err == nil {
+			systemPrompt, err = render("system_prompt", systemPrompt)
+		}
+		for i := range turns {
+			if err != nil {
+				break
+			}
+			turns[i].Content, err = render(fmt.Sprintf("message %d", i+1), turns[i].Content)
+		}
+		if err != nil {
+			logger.Error("prompt rendering failed", "error", err)
+			return err
+		}
+	}
+
+	// Build messages for OpenAI
+	if cfg.Gate {
+		systemPrompt += "\n\n" + gate.Instructions
+	}
+	messages := []openai.Message{
+		{
+			Role:    "system",
+			Content: systemPrompt,
+		},
+	}
+	messages = append(messages, prompt.Messages(turns)...)
+
+	// Fit the attachments into the model's context window, or split them
+	// into chunks for map-reduce when they do not fit
+	var runner *mapreduce.Runner
+	var chunks [][]file.Attachment
+	if limit := inputBudget(cfg); limit > 0 && len(in.attachments) > 0 {
+		counter := tokenizer.ForModel(cfg.Model, cfg.TokenizerDir, logger)
+		fixed := []string{systemPrompt, userPrompt}
+		for _, turn := range turns {
+			fixed = append(fixed, turn.Content)
+		}
+		tokenBudget := budget.New(limit, policy, counter, logger)
+		fitted, err := tokenBudget.Fit(fixed, in.attachments)
+		switch {
+		case errors.Is(err, budget.ErrOverBudget) && policy == budget.PolicyMapReduce:
+			runner = mapreduce.NewRunner(provider, counter, tokenBudget.Available(fixed), cfg.MaxConcurrency, logger)
+			chunks = runner.Split(in.attachments)
+			if len(chunks) == 0 {
+				logger.Error("token budget exceeded", "budget", limit, "policy", policy, "error", mapreduce.ErrNoText)
+				return fmt.Errorf("error applying token budget: %w: %w", mapreduce.ErrNoText, err)
+			}
+			logger.Info("input split for map-reduce", "chunks", len(chunks), "budget", limit)
+		case err != nil:
+			logger.Error("token budget exceeded", "budget", limit, "policy", policy, "error", err)
+			return fmt.Errorf("error applying token budget: %w", err)
+		default:
+			in.attachments = fitted
+		}
+	}
+	if runner == nil {
+		messages = append(messages, in.render(userPrompt, in.attachments))
+	}
+
+	request := openai.ChatCompletionRequest{
+		Model:       cfg.RequestModel(),
 		Messages:    messages,
 		Temperature: cfg.Temperature,
 		MaxTokens:   int64(cfg.MaxTokens),
-	})
+		Stream:      cfg.Stream,
+	}
+	if responseSchema != nil {
+		request.ResponseSchema = &openai.JSONSchema{
+			Name:   responseSchema.Name,
+			Schema: responseSchema.Document,
+		}
+	}
+	printer := output.NewStreamPrinter()
+	if cfg.Stream {
+		request.OnToken = printer.Print
+	}
+
+	// Call the LLM provider
+	logger.Info("calling provider", "provider", cfg.Provider)
+	start := time.Now()
+	var response *openai.ChatCompletionResponse
+	switch {
+	case runner != nil:
+		response, err = runner.Run(ctx, request, userPrompt, chunks, in.render)
+	case toolAgent != nil:
+		response, err = toolAgent.Run(ctx, request)
+	default:
+		response, err = provider.CreateChatCompletion(ctx, request)
+	}
 	if err != nil {
-		logger.Error("openai api call failed", "error", err)
-		return fmt.Errorf("error calling OpenAI: %w", err)
+		logger.Error("provider call failed", "provider", cfg.Provider, "error", err)
+		return fmt.Errorf("error calling %s: %w", cfg.Provider, err)
+	}
+
+	// Validate structured output before anything is written
+	content := response.Content
+	if responseSchema != nil {
+		content, err = responseSchema.Validate(content)
+		if err != nil {
+			logger.Error("response validation failed", "schema", responseSchema.Name, "error", err)
+			return fmt.Errorf("error validating response: %w", err)
+		}
+		logger.Info("response matches schema", "schema", responseSchema.Name)
+	}
+
+	var verdict *gate.Verdict
+	if cfg.Gate {
+		verdict, err = gate.Parse(content)
+		if err != nil {
+			logger.Error("verdict parsing failed", "error", err)
+			return fmt.Errorf("error reading verdict: %w", err)
+		}
 	}
 
 	// Output the response
-	if err := outputWriter.WriteResponse(response.Content, response.Usage, cfg.OutputFile); err != nil {
+	model := response.Model
+	if model == "" {
+		model = cfg.RequestModel()
+	}
+	logger.Info("response received", "model", model, "requested_model", cfg.RequestModel())
+	result := &output.Result{
+		Content:        content,
+		Model:          model,
+		RequestedModel: cfg.RequestModel(),
+		FinishReason:   response.FinishReason,
+		Usage:          response.Usage,
+		Duration:       time.Since(start),
+		Build:          cfg.Build,
+		Streamed:       printer.Started(),
+		Verdict:        verdict,
+		FailOn:         failOn,
+	}
+	if err := outputWriter.Write(result); err != nil {
 		logger.Error("output writing failed", "error", err)
 		return fmt.Errorf("error writing output: %w", err)
 	}
 
+	// Fail the build when the verdict has blocking findings
+	if verdict != nil {
+		blocking := verdict.Blocking(failOn)
+		logger.Info("quality gate evaluated",
+			"verdict", verdict.Verdict,
+			"findings", len(verdict.Findings),
+			"blocking", len(blocking),
+			"fail_on", failOn.String(),
+		)
+		if len(blocking) > 0 {
+			for _, f := range blocking {
+				logger.Error("blocking finding", "severity", f.Severity, "title", f.Title, "path", f.Path, "line", f.Line)
+			}
+			return fmt.Errorf("quality gate failed: %d finding(s) at or above %s severity", len(blocking), failOn)
+		}
+	}
+
 	logger.Info("plugin execution completed successfully")
 	fmt.Println("\n✓ OpenAI plugin execution completed successfully")
 	return nil
@@ -1,8 +1,20 @@
 package plugin
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/config"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/mapreduce"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/openai"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/tokenizer"
 )
 
 func TestRun_MissingAPIKey(t *testing.T) {
@@ -49,17 +61,668 @@ func TestRun_NonexistentFile(t *testing.T) {
 	}
 }
 
-// Note: We cannot easily test the full Run() function with actual OpenAI API calls
-// in unit tests without mocking. The tests above verify the basic validation logic.
-// For full integration testing with the OpenAI API, we would need:
-// 1. A valid API key
-// 2. Network access
-// 3. Mocking infrastructure or dependency injection
-//
-// In a production environment, you would typically:
-// - Use interfaces for the OpenAI client to enable mocking
-// - Create integration tests that run separately from unit tests
-// - Use test doubles or stubs for external dependencies
+func TestRun_UnsupportedOutputFormat(t *testing.T) {
+	// Clear environment
+	clearPluginEnv()
+	defer clearPluginEnv()
+
+	os.Setenv("PLUGIN_API_KEY", "test-key")
+	os.Setenv("PLUGIN_PROMPT", "test prompt")
+	os.Setenv("PLUGIN_OUTPUT_FORMAT", "xml")
+
+	err := Run()
+	if err == nil {
+		t.Error("Expected error for unsupported output format, got nil")
+	}
+}
+
+func TestRun_UnsupportedProvider(t *testing.T) {
+	// Clear environment
+	clearPluginEnv()
+	defer clearPluginEnv()
+
+	os.Setenv("PLUGIN_PROVIDER", "unknown")
+	os.Setenv("PLUGIN_API_KEY", "test-key")
+	os.Setenv("PLUGIN_PROMPT", "test prompt")
+
+	err := Run()
+	if err == nil {
+		t.Error("Expected error for unsupported provider, got nil")
+	}
+}
+
+// stubProvider is a Provider that records the request and returns a canned response
+type stubProvider struct {
+	request  openai.ChatCompletionRequest
+	response *openai.ChatCompletionResponse
+	err      error
+}
+
+func (s *stubProvider) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (*openai.ChatCompletionResponse, error) {
+	s.request = req
+	return s.response, s.err
+}
+
+func testConfig(t *testing.T) *config.Config {
+	clearPluginEnv()
+	t.Cleanup(clearPluginEnv)
+	os.Setenv("PLUGIN_API_KEY", "test-key")
+	os.Setenv("PLUGIN_PROMPT", "test prompt")
+	return config.Load()
+}
+
+func TestExecute_WritesProviderResponse(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.OutputFile = filepath.Join(t.TempDir(), "result.txt")
+
+	provider := &stubProvider{
+		response: &openai.ChatCompletionResponse{
+			Content: "stub answer",
+			Model:   "gpt-4o-mini",
+			Usage:   openai.Usage{TotalTokens: 3},
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	if err := execute(cfg, provider, logger); err != nil {
+		t.Fatalf("execute() error = %v", err)
+	}
+
+	if len(provider.request.Messages) != 2 {
+		t.Fatalf("Expected system and user messages, got %d", len(provider.request.Messages))
+	}
+	if provider.request.Messages[1].Content != "test prompt" {
+		t.Errorf("User message = %v, want prompt", provider.request.Messages[1].Content)
+	}
+
+	data, err := os.ReadFile(cfg.OutputFile)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if string(data) != "stub answer" {
+		t.Errorf("Output file = %q, want %q", string(data), "stub answer")
+	}
+}
+
+func TestExecute_Files(t *testing.T) {
+	cfg := testConfig(t)
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a.go": "package a", "b.go": "package b"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	cfg.Files = []string{filepath.Join(dir, "*.go")}
+
+	provider := &stubProvider{
+		response: &openai.ChatCompletionResponse{Content: "stub answer"},
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	if err := execute(cfg, provider, logger); err != nil {
+		t.Fatalf("execute() error = %v", err)
+	}
+
+	if len(provider.request.Messages) != 2 {
+		t.Fatalf("Expected system and user messages, got %d", len(provider.request.Messages))
+	}
+	content, _ := provider.request.Messages[1].Content.(string)
+	if !strings.Contains(content, "package a") || !strings.Contains(content, "package b") {
+		t.Errorf("User message should contain every matched file, got %q", content)
+	}
+}
+
+func TestExecute_TokenBudget(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     string
+		wantErr    bool
+		wantCalled bool
+	}{
+		{"error policy fails before the request", "error", true, false},
+		{"drop files fails when no file fits", "drop_files", true, false},
+		{"truncate tail shortens the file", "truncate_tail", false, true},
+		{"invalid policy", "summarize", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.FilePath = filepath.Join(t.TempDir(), "big.txt")
+			if err := os.WriteFile(cfg.FilePath, []byte(strings.Repeat("some long line of text\n", 500)), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+			cfg.MaxInputTokens = 200
+			cfg.BudgetPolicy = tt.policy
+
+			provider := &stubProvider{
+				response: &openai.ChatCompletionResponse{Content: "stub answer"},
+			}
+			logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+			err := execute(cfg, provider, logger)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			called := provider.request.Messages != nil
+			if called != tt.wantCalled {
+				t.Fatalf("Provider called = %v, want %v", called, tt.wantCalled)
+			}
+			if called {
+				content, _ := provider.request.Messages[1].Content.(string)
+				if !strings.Contains(content, "truncated to fit the token budget") {
+					t.Errorf("Expected a truncated file, got %d bytes", len(content))
+				}
+			}
+		})
+	}
+}
+
+func TestExecute_MapReduce(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.FilePath = filepath.Join(t.TempDir(), "big.log")
+	if err := os.WriteFile(cfg.FilePath, []byte(strings.Repeat("some long line of text\n", 500)), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	cfg.MaxInputTokens = 1000
+	cfg.BudgetPolicy = "map_reduce"
+	cfg.MaxConcurrency = 1
+
+	provider := &stubProvider{
+		response: &openai.ChatCompletionResponse{Content: "partial", Usage: openai.Usage{TotalTokens: 10}},
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	if err := execute(cfg, provider, logger); err != nil {
+		t.Fatalf("execute() error = %v", err)
+	}
+
+	// The last request is the reduce over the partial answers
+	content, _ := provider.request.Messages[1].Content.(string)
+	if !strings.Contains(content, `<answer part="1">`) || !strings.Contains(content, `<answer part="2">`) {
+		t.Errorf("Expected a reduce request over several parts, got %q", content)
+	}
+}
+
+// gitRepo creates a repository with main.go committed on main and a feature
+// branch changing it from oldContent to newContent. It returns the directory
+// and the commits before and after the change.
+func gitRepo(t *testing.T, oldContent, newContent string) (dir, before, after string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir = t.TempDir()
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write main.go: %v", err)
+		}
+	}
+
+	run("init", "--quiet", "--initial-branch=main")
+	write(oldContent)
+	run("add", "-A")
+	run("commit", "--quiet", "-m", "initial")
+	before = run("rev-parse", "HEAD")
+
+	run("checkout", "--quiet", "-b", "feature")
+	write(newContent)
+	run("commit", "--quiet", "-am", "change")
+	after = run("rev-parse", "HEAD")
+	return dir, before, after
+}
+
+func TestExecute_Diff(t *testing.T) {
+	dir, before, after := gitRepo(t,
+		"package main\n\nfunc main() {\n\tprintln(\"one\")\n}\n",
+		"package main\n\nfunc main() {\n\tprintln(\"two\")\n}\n",
+	)
+
+	tests := []struct {
+		name  string
+		build config.Build
+	}{
+		{
+			// Drone sets the target branch to the pushed branch on pushes
+			name:  "push with target branch of the pushed branch",
+			build: config.Build{Event: "push", Branch: "feature", TargetBranch: "feature", CommitBefore: before, CommitAfter: after},
+		},
+		{
+			name:  "pull request against target branch",
+			build: config.Build{Event: "pull_request", PullRequest: "1", Branch: "feature", TargetBranch: "main", CommitAfter: after},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.Source = "diff"
+			cfg.Build = tt.build
+			cfg.Build.Workspace = dir
+
+			provider := &stubProvider{
+				response: &openai.ChatCompletionResponse{Content: "stub answer"},
+			}
+			logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+			if err := execute(cfg, provider, logger); err != nil {
+				t.Fatalf("execute() error = %v", err)
+			}
+			if provider.request.Messages == nil {
+				t.Fatal("Expected the diff to be reviewed, provider was not called")
+			}
+			content, _ := provider.request.Messages[1].Content.(string)
+			if !strings.Contains(content, "+\tprintln(\"two\")") {
+				t.Errorf("User message should contain the change, got %q", content)
+			}
+		})
+	}
+}
+
+func TestLoadDiff_SplitFile(t *testing.T) {
+	var oldLines, newLines []string
+	for i := 1; i <= 200; i++ {
+		line := fmt.Sprintf("var v%d = %d", i, i)
+		oldLines = append(oldLines, line)
+		if i == 10 || i == 190 {
+			line += " // changed"
+		}
+		newLines = append(newLines, line)
+	}
+	dir, before, after := gitRepo(t, strings.Join(oldLines, "\n")+"\n", strings.Join(newLines, "\n")+"\n")
+
+	cfg := testConfig(t)
+	cfg.Source = "diff"
+	cfg.Build = config.Build{Event: "push", CommitBefore: before, CommitAfter: after, Workspace: dir}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	in, err := loadDiff(context.Background(), cfg, logger)
+	if err != nil {
+		t.Fatalf("loadDiff() error = %v", err)
+	}
+
+	// A limit that fits one hunk but not both splits the file in two
+	counter := tokenizer.ForModel(cfg.Model, "", logger)
+	runner := mapreduce.NewRunner(&stubProvider{}, counter, 120, 1, logger)
+	chunks := runner.Split(in.attachments)
+	if len(chunks) != 2 {
+		t.Fatalf("Expected the patch to be split into 2 chunks, got %d", len(chunks))
+	}
+	for i, chunk := range chunks {
+		content, _ := in.render("Review", chunk).Content.(string)
+		if strings.Count(content, "diff --git a/main.go b/main.go") != 1 || !strings.Contains(content, "+++ b/main.go") {
+			t.Errorf("Chunk %d should carry the file header once, got %q", i+1, content)
+		}
+	}
+	second, _ := in.render("Review", chunks[1]).Content.(string)
+	if !strings.Contains(second, "var v190 = 190 // changed") {
+		t.Errorf("Second chunk should hold the second hunk, got %q", second)
+	}
+}
+
+func TestExecute_MapReduceImagesOnly(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.FilePath = filepath.Join(t.TempDir(), "screenshot.png")
+	if err := os.WriteFile(cfg.FilePath, []byte(strings.Repeat("png", 5000)), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	cfg.MaxInputTokens = 50
+	cfg.BudgetPolicy = "map_reduce"
+
+	provider := &stubProvider{
+		response: &openai.ChatCompletionResponse{Content: "stub answer"},
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	err := execute(cfg, provider, logger)
+	if !errors.Is(err, mapreduce.ErrNoText) {
+		t.Fatalf("execute() error = %v, want ErrNoText", err)
+	}
+	if provider.request.Messages != nil {
+		t.Error("Provider should not be called without text to split")
+	}
+}
+
+func TestInputBudget(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want int
+	}{
+		{"explicit limit", config.Config{Model: "gpt-4o", MaxInputTokens: 5000, MaxTokens: 1000}, 5000},
+		{"known context window", config.Config{Model: "gpt-4o", MaxTokens: 1000}, 127000},
+		{"smallest fallback window", config.Config{Model: "gpt-4o", FallbackModels: []string{"gpt-4"}, MaxTokens: 1000}, 7192},
+		{"unknown model", config.Config{Model: "llama3.2", MaxTokens: 1000}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inputBudget(&tt.cfg); got != tt.want {
+				t.Errorf("inputBudget() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecute_PromptTemplate(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Prompt = "Review PR #{{ .PullRequest }} on {{ .Repo }}"
+	cfg.SystemPrompt = "You review code for {{ .Repo }}."
+	cfg.Build.Repo = "octocat/hello-world"
+	cfg.Build.PullRequest = "42"
+
+	provider := &stubProvider{
+		response: &openai.ChatCompletionResponse{Content: "stub answer"},
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	if err := execute(cfg, provider, logger); err != nil {
+		t.Fatalf("execute() error = %v", err)
+	}
+	if got := provider.request.Messages[0].Content; got != "You review code for octocat/hello-world." {
+		t.Errorf("System message = %v", got)
+	}
+	if got := provider.request.Messages[1].Content; got != "Review PR #42 on octocat/hello-world" {
+		t.Errorf("User message = %v", got)
+	}
+
+	// Prompts quoting Helm or Go template syntax fail to parse or to
+	// execute and are sent verbatim
+	helm := []string{
+		"Explain the Helm expression {{ .Values.image | quote",
+		"Why is {{ .Values.x }} empty in this chart?",
+		"Name the resources {{ .Release.Name }}-web",
+		`Where is {{ template "x" }} defined?`,
+	}
+	for _, text := range helm {
+		cfg.Prompt = text
+		if err := execute(cfg, provider, logger); err != nil {
+			t.Fatalf("execute() error = %v for %q", err, text)
+		}
+		if got := provider.request.Messages[1].Content; got != text {
+			t.Errorf("User message = %v, want verbatim prompt", got)
+		}
+	}
+
+	// unless templating was enabled explicitly
+	os.Setenv("PLUGIN_TEMPLATE", "true")
+	for _, text := range helm {
+		cfg.Prompt = text
+		if err := execute(cfg, provider, logger); err == nil {
+			t.Errorf("Expected template error for %q, got nil", text)
+		}
+	}
+
+	// With templating disabled the prompt is sent verbatim
+	cfg.Prompt = "Review PR #{{ .PullRequest }} on {{ .Repo }}"
+	cfg.Template = false
+	if err := execute(cfg, provider, logger); err != nil {
+		t.Fatalf("execute() error = %v", err)
+	}
+	if got := provider.request.Messages[1].Content; got != cfg.Prompt {
+		t.Errorf("User message = %v, want verbatim prompt", got)
+	}
+}
+
+func TestExecute_PromptCatalog(t *testing.T) {
+	dir := t.TempDir()
+	definition := "---\nmodel: gpt-4.1\ntemperature: 0.1\nmax_tokens: 500\nsystem_prompt: You review {{ .Repo }}.\n---\nReview PR #{{ .PullRequest }}\n"
+	if err := os.MkdirAll(filepath.Join(dir, "review"), 0755); err != nil {
+		t.Fatalf("Failed to create catalog: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "review", "pr.md"), []byte(definition), 0644); err != nil {
+		t.Fatalf("Failed to create prompt file: %v", err)
+	}
+
+	cfg := testConfig(t)
+	cfg.Prompt = ""
+	cfg.PromptName = "review/pr"
+	cfg.PromptDir = dir
+	cfg.Build.Repo = "octocat/hello-world"
+	cfg.Build.PullRequest = "42"
+	// Explicit step settings take precedence over the front matter
+	os.Setenv("PLUGIN_MAX_TOKENS", "100")
+	cfg.MaxTokens = 100
+
+	provider := &stubProvider{
+		response: &openai.ChatCompletionResponse{Content: "stub answer"},
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	if err := execute(cfg, provider, logger); err != nil {
+		t.Fatalf("execute() error = %v", err)
+	}
+	req := provider.request
+	if req.Model != "gpt-4.1" || req.Temperature != 0.1 || req.MaxTokens != 100 {
+		t.Errorf("Request settings = %s, %v, %d", req.Model, req.Temperature, req.MaxTokens)
+	}
+	if got := req.Messages[0].Content; got != "You review octocat/hello-world." {
+		t.Errorf("System message = %v", got)
+	}
+	if got := req.Messages[1].Content; got != "Review PR #42" {
+		t.Errorf("User message = %v", got)
+	}
+
+	// A system prompt file replaces the system prompt of the definition
+	systemFile := filepath.Join(dir, "system.txt")
+	if err := os.WriteFile(systemFile, []byte("Be strict.\n"), 0644); err != nil {
+		t.Fatalf("Failed to create system prompt file: %v", err)
+	}
+	cfg.SystemFile = systemFile
+	if err := execute(cfg, provider, logger); err != nil {
+		t.Fatalf("execute() error = %v", err)
+	}
+	if got := provider.request.Messages[0].Content; got != "Be strict." {
+		t.Errorf("System message = %v, want the system prompt file", got)
+	}
+
+	cfg.PromptName = "missing"
+	if err := execute(cfg, provider, logger); err == nil {
+		t.Error("Expected error for a prompt missing from the catalog, got nil")
+	}
+}
+
+func TestExecute_Messages(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Messages = `[{"role": "user", "content": "Review {{ .Repo }}"}, {"role": "assistant", "content": "- [high] main.go: bug"}]`
+	cfg.Build.Repo = "octocat/example"
+
+	provider := &stubProvider{
+		response: &openai.ChatCompletionResponse{Content: "stub answer"},
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	if err := execute(cfg, provider, logger); err != nil {
+		t.Fatalf("execute() error = %v", err)
+	}
+	messages := provider.request.Messages
+	if len(messages) != 4 {
+		t.Fatalf("Expected system, two example and user messages, got %d", len(messages))
+	}
+	wantRoles := []string{"system", "user", "assistant", "user"}
+	for i, role := range wantRoles {
+		if messages[i].Role != role {
+			t.Errorf("Message %d role = %s, want %s", i, messages[i].Role, role)
+		}
+	}
+	if messages[1].Content != "Review octocat/example" {
+		t.Errorf("Example should be rendered as a template, got %v", messages[1].Content)
+	}
+	if messages[3].Content != "test prompt" {
+		t.Errorf("Final message = %v, want the prompt", messages[3].Content)
+	}
+
+	cfg.Messages = `[{"role": "robot", "content": "Hi"}]`
+	if err := execute(cfg, provider, logger); err == nil {
+		t.Error("Expected error for an unsupported role, got nil")
+	}
+}
+
+// agentProvider calls read_file once and then answers with the tool result
+type agentProvider struct {
+	requests int
+}
+
+func (p *agentProvider) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (*openai.ChatCompletionResponse, error) {
+	p.requests++
+	last := req.Messages[len(req.Messages)-1]
+	if last.Role != "tool" {
+		return &openai.ChatCompletionResponse{
+			ToolCalls: []openai.ToolCall{{ID: "call_1", Name: "read_file", Arguments: `{"path": "main.go"}`}},
+		}, nil
+	}
+	return &openai.ChatCompletionResponse{Content: "read: " + last.Content.(string)}, nil
+}
+
+func TestExecute_Agent(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	cfg := testConfig(t)
+	cfg.Agent = true
+	cfg.Build.Workspace = dir
+	cfg.OutputFile = filepath.Join(t.TempDir(), "result.txt")
+
+	provider := &agentProvider{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	if err := execute(cfg, provider, logger); err != nil {
+		t.Fatalf("execute() error = %v", err)
+	}
+	if provider.requests != 2 {
+		t.Errorf("Expected a tool call and a final request, got %d requests", provider.requests)
+	}
+	data, err := os.ReadFile(cfg.OutputFile)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if string(data) != "read: 1\tpackage main\n" {
+		t.Errorf("Output file = %q, want the answer built from the tool result", data)
+	}
+
+	cfg.MaxIterations = 1
+	if err := execute(cfg, &agentProvider{}, logger); err == nil {
+		t.Error("Expected error when the iteration limit is reached, got nil")
+	}
+}
+
+func TestExecute_ProviderError(t *testing.T) {
+	cfg := testConfig(t)
+	provider := &stubProvider{err: errors.New("boom")}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	if err := execute(cfg, provider, logger); err == nil {
+		t.Error("Expected error from provider, got nil")
+	}
+}
+
+func TestExecute_ResponseSchema(t *testing.T) {
+	schemaJSON := `{"type": "object", "properties": {"verdict": {"type": "string"}}, "required": ["verdict"], "additionalProperties": false}`
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"matching response", `{"verdict": "pass"}`, false},
+		{"invalid response", `{"result": "pass"}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.ResponseSchema = schemaJSON
+			cfg.OutputFile = filepath.Join(t.TempDir(), "result.json")
+
+			provider := &stubProvider{
+				response: &openai.ChatCompletionResponse{Content: tt.content},
+			}
+			logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+			err := execute(cfg, provider, logger)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if provider.request.ResponseSchema == nil {
+				t.Fatal("Request should carry the response schema")
+			}
+			if _, statErr := os.Stat(cfg.OutputFile); tt.wantErr == (statErr == nil) {
+				t.Errorf("Output file written = %v, want %v", statErr == nil, !tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecute_Gate(t *testing.T) {
+	verdict := `{
+		"verdict": "fail",
+		"summary": "One injection risk",
+		"findings": [
+			{"severity": "medium", "rule": "sql-injection", "title": "SQL injection", "message": "query built with fmt.Sprintf", "path": "db.go", "line": 12, "end_line": 12}
+		]
+	}`
+
+	tests := []struct {
+		name    string
+		failOn  string
+		wantErr bool
+	}{
+		{"finding at threshold fails", "medium", true},
+		{"finding below threshold passes", "high", false},
+		{"invalid fail_on", "severe", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.Gate = true
+			cfg.FailOn = tt.failOn
+			cfg.OutputFormat = "json"
+			cfg.OutputFile = filepath.Join(t.TempDir(), "result.json")
+
+			provider := &stubProvider{
+				response: &openai.ChatCompletionResponse{Content: verdict},
+			}
+			logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+			err := execute(cfg, provider, logger)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.failOn == "severe" {
+				return
+			}
+			if provider.request.ResponseSchema == nil {
+				t.Fatal("Request should carry the verdict schema")
+			}
+			// The verdict is written even when the gate fails the build
+			data, readErr := os.ReadFile(cfg.OutputFile)
+			if readErr != nil {
+				t.Fatalf("Failed to read output file: %v", readErr)
+			}
+			if !strings.Contains(string(data), `"verdict": "fail"`) {
+				t.Errorf("Output should contain the verdict, got %s", data)
+			}
+		})
+	}
+}
+
+// Note: Full runs against the OpenAI API are covered by the integration tests
+// below, which require a valid API key and network access. Unit tests use
+// stubProvider in place of a real backend.
 
 func TestRun_ValidConfigButInvalidAPIKey(t *testing.T) {
 	if os.Getenv("RUN_INTEGRATION_TESTS") != "true" {
@@ -84,15 +747,54 @@ func TestRun_ValidConfigButInvalidAPIKey(t *testing.T) {
 // clearPluginEnv clears all PLUGIN_* environment variables
 func clearPluginEnv() {
 	envVars := []string{
+		"PLUGIN_PROVIDER",
 		"PLUGIN_API_KEY",
+		"PLUGIN_BASE_URL",
+		"PLUGIN_ORGANIZATION",
+		"PLUGIN_PROJECT",
+		"PLUGIN_EXTRA_HEADERS",
 		"PLUGIN_MODEL",
+		"PLUGIN_FALLBACK_MODELS",
 		"PLUGIN_PROMPT",
+		"PLUGIN_PROMPT_FILE",
+		"PLUGIN_PROMPT_NAME",
+		"PLUGIN_PROMPT_DIR",
 		"PLUGIN_FILE",
+		"PLUGIN_FILES",
+		"PLUGIN_SOURCE",
+		"PLUGIN_DIFF_CONTEXT",
+		"PLUGIN_MAX_INPUT_TOKENS",
+		"PLUGIN_BUDGET_POLICY",
+		"PLUGIN_TOKENIZER_DIR",
+		"PLUGIN_MAX_CONCURRENCY",
+		"PLUGIN_AGENT",
+		"PLUGIN_PUBLISH",
+		"PLUGIN_PUBLISH_TOKEN",
+		"PLUGIN_PUBLISH_API_URL",
+		"PLUGIN_PUBLISH_KEY",
+		"PLUGIN_PUBLISH_REVIEW",
+		"PLUGIN_MAX_ITERATIONS",
 		"PLUGIN_TEMPERATURE",
 		"PLUGIN_MAX_TOKENS",
 		"PLUGIN_SYSTEM_PROMPT",
+		"PLUGIN_SYSTEM_PROMPT_FILE",
+		"PLUGIN_MESSAGES",
+		"PLUGIN_TEMPLATE",
+		"PLUGIN_TEMPLATE_ENV",
+		"PLUGIN_RESPONSE_SCHEMA",
+		"PLUGIN_GATE",
+		"PLUGIN_FAIL_ON",
 		"PLUGIN_OUTPUT_FILE",
+		"PLUGIN_OUTPUT_FORMAT",
 		"PLUGIN_TIMEOUT",
+		"PLUGIN_STREAM",
+		"PLUGIN_MAX_RETRIES",
+		"PLUGIN_RETRY_BACKOFF",
+		"PLUGIN_RETRY_MAX_BACKOFF",
+		"PLUGIN_AZURE_ENDPOINT",
+		"PLUGIN_AZURE_DEPLOYMENT",
+		"PLUGIN_AZURE_API_VERSION",
+		"PLUGIN_AZURE_AD_TOKEN",
 	}
 	for _, key := range envVars {
 		os.Unsetenv(key)
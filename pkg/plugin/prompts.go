@@ -0,0 +1,68 @@
+package plugin
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/config"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/prompt"
+)
+
+// loadPrompts reads the prompt and system prompt from files or the prompt
+// catalog. Settings from the front matter of a definition apply only where
+// the step does not set them explicitly.
+func loadPrompts(cfg *config.Config, logger *slog.Logger) error {
+	var def *prompt.Definition
+	var source string
+	var err error
+	switch {
+	case cfg.PromptFile != "":
+		logger.Info("loading prompt file", "path", cfg.PromptFile)
+		source = cfg.PromptFile
+		def, err = prompt.LoadFile(cfg.PromptFile)
+	case cfg.PromptName != "":
+		logger.Info("loading prompt from catalog", "name", cfg.PromptName, "dir", cfg.PromptDir)
+		source = cfg.PromptName
+		def, err = prompt.Lookup(cfg.PromptDir, cfg.PromptName)
+	}
+	if err != nil {
+		return err
+	}
+
+	if def != nil {
+		if def.Prompt == "" {
+			return fmt.Errorf("prompt %q is empty", source)
+		}
+		cfg.Prompt = def.Prompt
+		if def.SystemPrompt != "" && !config.IsSet("SYSTEM_PROMPT") {
+			cfg.SystemPrompt = def.SystemPrompt
+		}
+		if def.Model != "" && !config.IsSet("MODEL") {
+			cfg.Model = def.Model
+		}
+		if def.Temperature != nil && !config.IsSet("TEMPERATURE") {
+			cfg.Temperature = *def.Temperature
+		}
+		if def.MaxTokens != nil && !config.IsSet("MAX_TOKENS") {
+			cfg.MaxTokens = *def.MaxTokens
+		}
+		if def.Schema != "" && !config.IsSet("RESPONSE_SCHEMA") {
+			if cfg.Gate {
+				return fmt.Errorf("prompt %q defines a response schema and cannot be combined with GATE", source)
+			}
+			cfg.ResponseSchema = def.Schema
+		}
+	}
+
+	if cfg.SystemFile != "" {
+		logger.Info("loading system prompt file", "path", cfg.SystemFile)
+		data, err := os.ReadFile(cfg.SystemFile)
+		if err != nil {
+			return fmt.Errorf("error reading system prompt file: %w", err)
+		}
+		cfg.SystemPrompt = strings.TrimSpace(string(data))
+	}
+	return nil
+}
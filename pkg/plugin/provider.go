@@ -0,0 +1,54 @@
+package plugin
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/anthropic"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/config"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/gemini"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/ollama"
+	"github.com/dewan-ahmed/drone-openai-plugin/internal/openai"
+)
+
+// newProvider creates the LLM provider selected by the configuration
+func newProvider(cfg *config.Config, logger *slog.Logger) (openai.Provider, error) {
+	switch cfg.Provider {
+	case "", "openai":
+		return openai.NewClient(openai.Options{
+			APIKey:       cfg.APIKey,
+			BaseURL:      cfg.BaseURL,
+			Organization: cfg.Organization,
+			Project:      cfg.Project,
+			Headers:      cfg.ExtraHeaders,
+		}, logger), nil
+	case "azure":
+		return openai.NewAzureClient(openai.AzureConfig{
+			Endpoint:   cfg.Azure.Endpoint,
+			APIVersion: cfg.Azure.APIVersion,
+			APIKey:     cfg.APIKey,
+			ADToken:    cfg.Azure.ADToken,
+			Headers:    cfg.ExtraHeaders,
+		}, logger), nil
+	case "anthropic":
+		return anthropic.NewClient(anthropic.Options{
+			APIKey:  cfg.APIKey,
+			BaseURL: cfg.BaseURL,
+			Headers: cfg.ExtraHeaders,
+		}, logger), nil
+	case "ollama":
+		return ollama.NewClient(ollama.Options{
+			APIKey:  cfg.APIKey,
+			BaseURL: cfg.BaseURL,
+			Headers: cfg.ExtraHeaders,
+		}, logger), nil
+	case "gemini":
+		return gemini.NewClient(gemini.Options{
+			APIKey:  cfg.APIKey,
+			BaseURL: cfg.BaseURL,
+			Headers: cfg.ExtraHeaders,
+		}, logger), nil
+	default:
+		return nil, fmt.Errorf("unsupported provider %q", cfg.Provider)
+	}
+}